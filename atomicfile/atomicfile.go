@@ -0,0 +1,55 @@
+package atomicfile
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Write copies the data to the file by the path. The data is written to a
+// temporary file in the same directory, synced and renamed over the file, so
+// readers never see a partial file and an interrupted write keeps the previous
+// one. Missing parent directories are created readable only by the owner.
+func Write(path string, r io.Reader, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("cannot create directory: %w", err)
+	}
+
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("cannot create temporary file: %w", err)
+	}
+
+	err = write(tmp, r, perm)
+	if closeErr := tmp.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("cannot close temporary file: %w", closeErr)
+	}
+	if err == nil {
+		if err = os.Rename(tmp.Name(), path); err != nil {
+			err = fmt.Errorf("cannot replace file: %w", err)
+		}
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	return nil
+}
+
+func write(f *os.File, r io.Reader, perm os.FileMode) error {
+	if err := f.Chmod(perm); err != nil {
+		return fmt.Errorf("cannot set file mode: %w", err)
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		return fmt.Errorf("cannot write temporary file: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("cannot sync temporary file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+//go:build unit
+// +build unit
+
+package atomicfile_test
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/require"
+
+	"reactor-crw/atomicfile"
+)
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state", "index.json")
+
+	t.Log("Given the need to replace a file atomically.")
+	{
+		t.Log("When the file doesn't exist.")
+		{
+			require.NoError(t, atomicfile.Write(path, strings.NewReader("first"), 0600))
+
+			data, err := ioutil.ReadFile(path)
+			require.NoError(t, err)
+			require.Equal(t, "first", string(data))
+
+			info, err := os.Stat(path)
+			require.NoError(t, err)
+			require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+			info, err = os.Stat(filepath.Dir(path))
+			require.NoError(t, err)
+			require.Equal(t, os.FileMode(0700), info.Mode().Perm(), "Missing directories should be private")
+		}
+
+		t.Log("When the file is replaced.")
+		{
+			require.NoError(t, atomicfile.Write(path, strings.NewReader("second"), 0644))
+
+			data, err := ioutil.ReadFile(path)
+			require.NoError(t, err)
+			require.Equal(t, "second", string(data))
+
+			info, err := os.Stat(path)
+			require.NoError(t, err)
+			require.Equal(t, os.FileMode(0644), info.Mode().Perm())
+		}
+
+		t.Log("When the data cannot be read.")
+		{
+			r := io.MultiReader(strings.NewReader("partial"), iotest.ErrReader(errors.New("connection reset")))
+
+			require.Error(t, atomicfile.Write(path, r, 0600))
+
+			data, err := ioutil.ReadFile(path)
+			require.NoError(t, err)
+			require.Equal(t, "second", string(data), "The previous file should be kept")
+
+			files, err := ioutil.ReadDir(filepath.Dir(path))
+			require.NoError(t, err)
+			require.Len(t, files, 1, "Temporary files should be removed")
+		}
+	}
+}
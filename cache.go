@@ -0,0 +1,173 @@
+package reactor_crw
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"reactor-crw/atomicfile"
+	"reactor-crw/logging"
+)
+
+// ErrNotCached returned by CachedTransport in the offline mode when the data
+// isn't cached.
+var ErrNotCached = errors.New("not cached")
+
+// mediaExtensions lists extensions of content that isn't cached by default.
+var mediaExtensions = map[string]struct{}{
+	".jpg": {}, ".jpeg": {}, ".png": {}, ".gif": {}, ".webp": {},
+	".mp4": {}, ".webm": {},
+}
+
+// CachedTransport wraps a Transport and keeps fetched pages on disk, so
+// crawling the same pages again doesn't make requests. Media is fetched
+// without caching unless CacheMedia is set. It's safe for concurrent use.
+type CachedTransport struct {
+	// TTL is the time cached data is used for. Zero means it never expires.
+	TTL time.Duration
+
+	// Offline disables requests, so all data is read from the cache even if
+	// it's expired. ErrNotCached is returned for data that isn't cached.
+	Offline bool
+
+	// CacheMedia enables caching media content like images and videos.
+	CacheMedia bool
+
+	// Logger receives a record for each cache hit. It's optional.
+	Logger logging.Logger
+
+	t   Transport
+	dir string
+}
+
+// NewCachedTransport creates a new *CachedTransport keeping data in the
+// directory. The directory is created if it doesn't exist. It's readable only
+// by its owner since pages are fetched with the session of the user.
+func NewCachedTransport(t Transport, dir string) (*CachedTransport, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("cannot create cache directory: %w", err)
+	}
+
+	return &CachedTransport{
+		t:   t,
+		dir: dir,
+	}, nil
+}
+
+// FetchData returns the cached data if it's not expired. Otherwise, it
+// fetches the data using the wrapped transport and caches it.
+func (t *CachedTransport) FetchData(url string) (io.ReadCloser, error) {
+	if !t.cacheable(url) {
+		if t.Offline {
+			return nil, fmt.Errorf("cannot fetch %s: %w", url, ErrNotCached)
+		}
+		return t.t.FetchData(url)
+	}
+
+	name := t.path(url)
+	if f, ok := t.open(name); ok {
+		t.log().Debug("cache hit", "url", url)
+		return f, nil
+	}
+
+	if t.Offline {
+		return nil, fmt.Errorf("cannot fetch %s: %w", url, ErrNotCached)
+	}
+
+	body, err := t.t.FetchData(url)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = body.Close()
+	}()
+
+	// The body is streamed to the cache file, so large media isn't kept in
+	// memory, and the file is returned instead.
+	if err = t.save(name, body); err != nil {
+		return nil, fmt.Errorf("cannot cache %s: %w", url, err)
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("cannot cache %s: %w", url, err)
+	}
+
+	return f, nil
+}
+
+// Probe probes the content using the wrapped transport. ErrNotCached is
+// returned in the offline mode and ErrProbeUnsupported is returned if the
+// wrapped transport isn't a Prober.
+func (t *CachedTransport) Probe(url string) (ContentInfo, error) {
+	if t.Offline {
+		return ContentInfo{}, fmt.Errorf("cannot probe %s: %w", url, ErrNotCached)
+	}
+
+	p, ok := t.t.(Prober)
+	if !ok {
+		return ContentInfo{}, ErrProbeUnsupported
+	}
+
+	return p.Probe(url)
+}
+
+// cacheable reports whether data by the URL should be cached.
+func (t *CachedTransport) cacheable(rawURL string) bool {
+	if t.CacheMedia {
+		return true
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	_, media := mediaExtensions[strings.ToLower(path.Ext(u.Path))]
+
+	return !media
+}
+
+// path returns the path of the cache file of the URL.
+func (t *CachedTransport) path(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	key := hex.EncodeToString(sum[:])
+
+	return filepath.Join(t.dir, key[:2], key)
+}
+
+// open opens the cache file if it exists and isn't expired. Expired files are
+// used in the offline mode as well.
+func (t *CachedTransport) open(name string) (io.ReadCloser, bool) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, false
+	}
+	if !t.Offline && t.TTL > 0 && time.Since(info.ModTime()) > t.TTL {
+		return nil, false
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, false
+	}
+
+	return f, true
+}
+
+// save copies the data to the cache file, so concurrent readers never see a
+// partial file.
+func (t *CachedTransport) save(name string, r io.Reader) error {
+	return atomicfile.Write(name, r, 0600)
+}
+
+func (t *CachedTransport) log() logging.Logger {
+	return logging.OrDiscard(t.Logger)
+}
@@ -0,0 +1,137 @@
+//go:build unit
+// +build unit
+
+package reactor_crw
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCachedTransport_FetchData(t *testing.T) {
+	body := func(s string) io.ReadCloser { return ioutil.NopCloser(strings.NewReader(s)) }
+	read := func(rc io.ReadCloser, err error) string {
+		require.NoError(t, err, "Wasn't expected an error during fetch")
+		defer rc.Close()
+		data, err := ioutil.ReadAll(rc)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	t.Log("Given the need to cache crawled pages.")
+	{
+		t.Log("When the page is fetched twice.")
+		{
+			trp := &transportMock{}
+			trp.On("FetchData", "http://joyreactor.cc/tag/art").Return(body("page"), nil).Once()
+
+			dir := filepath.Join(t.TempDir(), "cache")
+			ct, err := NewCachedTransport(trp, dir)
+			require.NoError(t, err)
+
+			require.Equal(t, "page", read(ct.FetchData("http://joyreactor.cc/tag/art")))
+			require.Equal(t, "page", read(ct.FetchData("http://joyreactor.cc/tag/art")))
+			trp.AssertExpectations(t)
+
+			info, err := os.Stat(dir)
+			require.NoError(t, err)
+			require.Equal(t, os.FileMode(0700), info.Mode().Perm(), "The cache should be readable only by its owner")
+		}
+
+		t.Log("When the cached page is expired.")
+		{
+			trp := &transportMock{}
+			trp.On("FetchData", "http://joyreactor.cc/tag/art").Return(body("old"), nil).Once()
+
+			ct, err := NewCachedTransport(trp, t.TempDir())
+			require.NoError(t, err)
+			ct.TTL = time.Hour
+
+			require.Equal(t, "old", read(ct.FetchData("http://joyreactor.cc/tag/art")))
+
+			past := time.Now().Add(-2 * time.Hour)
+			require.NoError(t, os.Chtimes(ct.path("http://joyreactor.cc/tag/art"), past, past))
+
+			trp.On("FetchData", "http://joyreactor.cc/tag/art").Return(body("new"), nil).Once()
+			require.Equal(t, "new", read(ct.FetchData("http://joyreactor.cc/tag/art")))
+			trp.AssertExpectations(t)
+
+			t.Log("When the expired page is fetched offline.")
+			{
+				require.NoError(t, os.Chtimes(ct.path("http://joyreactor.cc/tag/art"), past, past))
+				ct.Offline = true
+
+				require.Equal(t, "new", read(ct.FetchData("http://joyreactor.cc/tag/art")))
+				trp.AssertExpectations(t)
+			}
+		}
+
+		t.Log("When media is fetched.")
+		{
+			trp := &transportMock{}
+			trp.On("FetchData", "http://img10.joyreactor.cc/pics/post/art.jpeg").Return(body("image"), nil).Once()
+			trp.On("FetchData", "http://img10.joyreactor.cc/pics/post/art.jpeg").Return(body("image"), nil).Once()
+
+			ct, err := NewCachedTransport(trp, t.TempDir())
+			require.NoError(t, err)
+
+			require.Equal(t, "image", read(ct.FetchData("http://img10.joyreactor.cc/pics/post/art.jpeg")))
+			require.Equal(t, "image", read(ct.FetchData("http://img10.joyreactor.cc/pics/post/art.jpeg")))
+			trp.AssertExpectations(t)
+		}
+
+		t.Log("When the page isn't cached in the offline mode.")
+		{
+			trp := &transportMock{}
+
+			ct, err := NewCachedTransport(trp, t.TempDir())
+			require.NoError(t, err)
+			ct.Offline = true
+
+			_, err = ct.FetchData("http://joyreactor.cc/tag/art")
+			require.True(t, errors.Is(err, ErrNotCached))
+			trp.AssertNotCalled(t, "FetchData", "http://joyreactor.cc/tag/art")
+		}
+
+		t.Log("When the wrapped transport fails.")
+		{
+			trp := &transportMock{}
+			trp.On("FetchData", "http://joyreactor.cc/tag/art").Return(body(""), &StatusError{URL: "http://joyreactor.cc/tag/art", Code: 503}).Twice()
+
+			ct, err := NewCachedTransport(trp, t.TempDir())
+			require.NoError(t, err)
+
+			_, err = ct.FetchData("http://joyreactor.cc/tag/art")
+			require.Error(t, err)
+			_, err = ct.FetchData("http://joyreactor.cc/tag/art")
+			require.Error(t, err, "Errors shouldn't be cached")
+			trp.AssertExpectations(t)
+		}
+
+		t.Log("When the body fails while it's read.")
+		{
+			broken := ioutil.NopCloser(io.MultiReader(strings.NewReader("par"), iotest.ErrReader(errors.New("connection reset"))))
+
+			trp := &transportMock{}
+			trp.On("FetchData", "http://joyreactor.cc/tag/art").Return(broken, nil).Once()
+			trp.On("FetchData", "http://joyreactor.cc/tag/art").Return(body("page"), nil).Once()
+
+			ct, err := NewCachedTransport(trp, t.TempDir())
+			require.NoError(t, err)
+
+			_, err = ct.FetchData("http://joyreactor.cc/tag/art")
+			require.Error(t, err)
+			require.Equal(t, "page", read(ct.FetchData("http://joyreactor.cc/tag/art")), "Partial bodies shouldn't be cached")
+			trp.AssertExpectations(t)
+		}
+	}
+}
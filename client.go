@@ -1,11 +1,15 @@
 package reactor_crw
 
 import (
+	"context"
+	"reactor-crw/event"
 	"reactor-crw/handler"
+	"reactor-crw/logging"
+	"reactor-crw/metrics"
 	"strings"
 	"sync"
-
-	"reactor-crw/parser"
+	"sync/atomic"
+	"time"
 )
 
 // Crawler is an interface for crawler used by the client. It fetches the content
@@ -13,7 +17,13 @@ import (
 // required content types. The crawler itself doesn't do anything with fetched
 // data but only collects content sources.
 type Crawler interface {
-	Fetch(path string, search []string) (parser.QueryResult, error)
+	Fetch(path string, search []string) ([]handler.Source, error)
+}
+
+// ContextCrawler is a Crawler that can stop crawling when the context is done.
+// Client.RunContext uses it instead of Crawler.Fetch if the crawler supports it.
+type ContextCrawler interface {
+	FetchContext(ctx context.Context, path string, search []string) ([]handler.Source, error)
 }
 
 // Client defines a facade for a specific crawler implementation and a list of
@@ -24,6 +34,18 @@ type Client struct {
 	Progress     chan int
 	Errors       chan error
 
+	// Events receives events about processing of each content source and the
+	// end of the run. It's optional.
+	Events event.Listener
+
+	// Metrics records results of processed content sources and utilization
+	// of the worker pool. It's optional.
+	Metrics *metrics.Metrics
+
+	// Logger receives records about the run and failed content sources. It's
+	// optional.
+	Logger logging.Logger
+
 	crawler    Crawler
 	handler    handler.ContentHandler
 	maxWorkers int
@@ -51,36 +73,143 @@ func NewClient(c Crawler, maxWorkers int, ch handler.ContentHandler) *Client {
 // Content sources will be processed by handler.ContentHandler through a simple
 // worker pool.
 func (c *Client) Run(path string, search string) error {
+	return c.RunContext(context.Background(), path, search)
+}
+
+// RunContext works the same way as Run but stops processing content sources
+// as soon as the context is done. Sources that are already being processed
+// will be finished. In this case the context error is returned. If the crawler
+// is a ContextCrawler, crawling is stopped as well.
+func (c *Client) RunContext(ctx context.Context, path string, search string) (err error) {
+	var (
+		found  int
+		failed int64
+		start  = time.Now()
+	)
+
+	c.log().Info("run started", "path", path, "search", search, "workers", c.maxWorkers)
+
 	defer func() {
 		close(c.Progress)
 		close(c.Errors)
+
+		attrs := []interface{}{
+			"path", path,
+			"found", found,
+			"failed", atomic.LoadInt64(&failed),
+			"duration", time.Since(start),
+		}
+		if err != nil {
+			c.log().Error("run failed", append(attrs, "error", err)...)
+		} else {
+			c.log().Info("run finished", attrs...)
+		}
+
+		e := event.Event{Type: event.RunFinished, Found: found, Failed: int(atomic.LoadInt64(&failed))}
+		if err != nil {
+			e.Error = err.Error()
+		}
+		event.Notify(c.Events, e)
 	}()
 
-	collectedData, err := c.crawler.Fetch(path, strings.Split(search, ","))
+	collectedData, err := c.fetch(ctx, path, strings.Split(search, ","))
+	found = len(collectedData)
 	c.TotalSources <- len(collectedData)
 	if err != nil || len(collectedData) == 0 {
 		return err
 	}
 
-	contentHandlerTasks := make(chan string, len(collectedData))
-	for task := range collectedData {
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	contentHandlerTasks := make(chan handler.Source, len(collectedData))
+	for _, task := range collectedData {
 		contentHandlerTasks <- task
 	}
 	close(contentHandlerTasks)
 
 	var wg sync.WaitGroup
 
+	c.Metrics.WorkersStarted(c.maxWorkers)
+	defer c.Metrics.WorkersStarted(-c.maxWorkers)
+
 	for i := 0; i < c.maxWorkers; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 			for t := range contentHandlerTasks {
-				c.handler.Process(t, c.Progress, c.Errors)
+				if ctx.Err() != nil {
+					return
+				}
+				if !c.process(t) {
+					atomic.AddInt64(&failed, 1)
+				}
 			}
 		}()
 	}
 
 	wg.Wait()
 
-	return nil
+	return ctx.Err()
+}
+
+// fetch fetches content sources with the crawler. The context is passed to
+// the crawler if it's a ContextCrawler.
+func (c *Client) fetch(ctx context.Context, path string, search []string) ([]handler.Source, error) {
+	if cc, ok := c.crawler.(ContextCrawler); ok {
+		return cc.FetchContext(ctx, path, search)
+	}
+
+	return c.crawler.Fetch(path, search)
+}
+
+// process passes the source to the handler and reports whether it was
+// processed without errors. If Client.Events is set each source is wrapped
+// with the corresponding events. If Client.Metrics or Client.Logger is set the
+// result of each source is recorded.
+func (c *Client) process(s handler.Source) bool {
+	defer c.Metrics.WorkerBusy()()
+
+	if c.Events == nil && c.Metrics == nil && c.Logger == nil {
+		c.handler.Process(s, c.Progress, c.Errors)
+		return true
+	}
+
+	c.log().Debug("processing source", "url", s.URL, "comment", s.Comment)
+	event.Notify(c.Events, event.Event{Type: event.ItemStarted, URL: s.URL, Comment: s.Comment})
+
+	var firstErr error
+	proxy := make(chan error)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		for err := range proxy {
+			if firstErr == nil && err != nil {
+				firstErr = err
+			}
+			c.Errors <- err
+		}
+	}()
+
+	c.handler.Process(s, c.Progress, proxy)
+	close(proxy)
+	<-done
+
+	if firstErr != nil {
+		c.Metrics.DownloadFailed(s.Type, firstErr)
+		c.log().Error("source failed", "url", s.URL, "error", firstErr)
+		event.Notify(c.Events, event.Event{Type: event.ItemFailed, URL: s.URL, Comment: s.Comment, Error: firstErr.Error()})
+		return false
+	}
+
+	c.Metrics.DownloadSucceeded(s.Type)
+	event.Notify(c.Events, event.Event{Type: event.ItemDone, URL: s.URL, Comment: s.Comment})
+
+	return true
+}
+
+func (c *Client) log() logging.Logger {
+	return logging.OrDiscard(c.Logger)
 }
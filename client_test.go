@@ -0,0 +1,210 @@
+//go:build unit
+// +build unit
+
+package reactor_crw
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"reactor-crw/event"
+	"reactor-crw/handler"
+	"reactor-crw/logging"
+	"reactor-crw/metrics"
+)
+
+type crawlerMock struct {
+	mock.Mock
+}
+
+func (m *crawlerMock) Fetch(path string, search []string) ([]handler.Source, error) {
+	args := m.Called(path, search)
+	return args.Get(0).([]handler.Source), args.Error(1)
+}
+
+type contentHandlerMock struct {
+	mock.Mock
+}
+
+func (m *contentHandlerMock) Process(s handler.Source, progress chan<- int, errors chan<- error) {
+	args := m.Called(s)
+	if err := args.Error(0); err != nil {
+		errors <- err
+	}
+	progress <- 1
+}
+
+type listenerMock struct {
+	mu     sync.Mutex
+	events []event.Event
+}
+
+func (m *listenerMock) Notify(e event.Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.events = append(m.events, e)
+}
+
+func (m *listenerMock) types() map[event.Type]int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	res := make(map[event.Type]int)
+	for _, e := range m.events {
+		res[e.Type]++
+	}
+
+	return res
+}
+
+func TestClient_RunContext(t *testing.T) {
+	sources := []handler.Source{{URL: "link_1"}, {URL: "link_2"}}
+
+	t.Log("Given the need to run the client.")
+	{
+		t.Log("When all sources should be processed.")
+		{
+			crw := &crawlerMock{}
+			crw.On("Fetch", "path", []string{"image", "gif"}).Return(sources, nil).Once()
+
+			ch := &contentHandlerMock{}
+			ch.On("Process", mock.Anything).Return(nil).Twice()
+
+			c := NewClient(crw, 2, ch)
+
+			go func() {
+				for range c.Progress {
+				}
+			}()
+
+			err := c.Run("path", "image,gif")
+			require.NoError(t, err, "Wasn't expected an error during run")
+			require.Equal(t, 2, <-c.TotalSources)
+			ch.AssertExpectations(t)
+		}
+
+		t.Log("When events are requested.")
+		{
+			crw := &crawlerMock{}
+			crw.On("Fetch", "path", []string{"image"}).Return(sources, nil).Once()
+
+			ch := &contentHandlerMock{}
+			ch.On("Process", handler.Source{URL: "link_1"}).Return(nil).Once()
+			ch.On("Process", handler.Source{URL: "link_2"}).Return(errors.New("error")).Once()
+
+			l := &listenerMock{}
+			c := NewClient(crw, 2, ch)
+			c.Events = l
+
+			go func() {
+				for range c.Progress {
+				}
+			}()
+			go func() {
+				for range c.Errors {
+				}
+			}()
+
+			err := c.Run("path", "image")
+			require.NoError(t, err, "Wasn't expected an error during run")
+
+			require.Equal(t, map[event.Type]int{
+				event.ItemStarted: 2,
+				event.ItemDone:    1,
+				event.ItemFailed:  1,
+				event.RunFinished: 1,
+			}, l.types())
+
+			last := l.events[len(l.events)-1]
+			require.Equal(t, event.RunFinished, last.Type)
+			require.Equal(t, 2, last.Found)
+			require.Equal(t, 1, last.Failed)
+		}
+
+		t.Log("When a logger is set.")
+		{
+			crw := &crawlerMock{}
+			crw.On("Fetch", "path", []string{"image"}).Return(sources[:1], nil).Once()
+
+			ch := &contentHandlerMock{}
+			ch.On("Process", handler.Source{URL: "link_1"}).Return(errors.New("cannot save")).Once()
+
+			buf := &bytes.Buffer{}
+			c := NewClient(crw, 1, ch)
+			c.Logger = logging.New(buf, logging.FormatText, logging.LevelInfo)
+
+			go func() {
+				for range c.Progress {
+				}
+			}()
+			go func() {
+				for range c.Errors {
+				}
+			}()
+
+			err := c.Run("path", "image")
+			require.NoError(t, err, "Wasn't expected an error during run")
+			require.Contains(t, buf.String(), `level=ERROR msg="source failed" url=link_1 error="cannot save"`)
+			require.Contains(t, buf.String(), `level=INFO msg="run finished" path=path found=1 failed=1`)
+		}
+
+		t.Log("When metrics are recorded.")
+		{
+			gif := handler.Source{URL: "http://img10.joyreactor.cc/pics/post/full/picture", Type: "gif"}
+
+			crw := &crawlerMock{}
+			crw.On("Fetch", "path", []string{"gif"}).Return([]handler.Source{gif}, nil).Once()
+
+			ch := &contentHandlerMock{}
+			ch.On("Process", gif).Return(nil).Once()
+
+			reg := prometheus.NewRegistry()
+			c := NewClient(crw, 1, ch)
+			c.Metrics = metrics.New(reg)
+
+			go func() {
+				for range c.Progress {
+				}
+			}()
+
+			err := c.Run("path", "gif")
+			require.NoError(t, err, "Wasn't expected an error during run")
+
+			expected := `
+				# HELP reactor_crw_downloads_total Amount of processed content sources by content type and result.
+				# TYPE reactor_crw_downloads_total counter
+				reactor_crw_downloads_total{result="success",type="gif"} 1
+			`
+			require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected), "reactor_crw_downloads_total"),
+				"Expected the type the source was found by rather than its extension")
+		}
+
+		t.Log("When the context is cancelled.")
+		{
+			crw := &crawlerMock{}
+			crw.On("Fetch", "path", []string{"image"}).Return(sources, nil).Once()
+
+			ch := &contentHandlerMock{}
+
+			c := NewClient(crw, 1, ch)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			err := c.RunContext(ctx, "path", "image")
+			require.ErrorIs(t, err, context.Canceled)
+			require.Equal(t, 2, <-c.TotalSources)
+			ch.AssertNotCalled(t, "Process", mock.Anything)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+
+	"reactor-crw/handler/filter"
+	"reactor-crw/handler/fs"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	limitRate    string
+	downloadRate string
+
+	// bandwidth is shared by file savers of all jobs. It stays nil unless
+	// the bandwidth is limited.
+	bandwidth *fs.Limiter
+
+	// downloadLimit is the bandwidth of each download in bytes per second.
+	downloadLimit int64
+)
+
+// addBandwidthFlags adds flags limiting the bandwidth of downloads to the
+// command.
+func addBandwidthFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&limitRate, "limit-rate", "", "Maximum bandwidth per second shared by all downloads. Example: 2M, 500K")
+	cmd.Flags().StringVar(&downloadRate, "limit-download-rate", "", "Maximum bandwidth per second of each download. Example: 500K")
+}
+
+// configBandwidth creates the limiters set with the bandwidth flags.
+func configBandwidth() error {
+	if limitRate != "" {
+		bps, err := parseRate(limitRate)
+		if err != nil {
+			return err
+		}
+		bandwidth = fs.NewLimiter(bps)
+	}
+
+	if downloadRate != "" {
+		bps, err := parseRate(downloadRate)
+		if err != nil {
+			return err
+		}
+		downloadLimit = bps
+	}
+
+	return nil
+}
+
+// parseRate converts a bandwidth like 2M to bytes per second.
+func parseRate(s string) (int64, error) {
+	bps, err := filter.ParseSize(s)
+	if err != nil {
+		return 0, err
+	}
+	if bps == 0 {
+		return 0, fmt.Errorf("invalid rate: %s", s)
+	}
+
+	return bps, nil
+}
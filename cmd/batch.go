@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// result contains the outcome of crawling a single target.
+type result struct {
+	target string
+	found  int
+	failed int
+	err    error
+}
+
+// resolveTargets returns the list of pages that should be crawled. It's either
+// a single page provided with --path or a list of pages read from --input.
+func resolveTargets() ([]string, error) {
+	if input == "" {
+		if path == "" {
+			return nil, errors.New("either --path or --input should be provided")
+		}
+		return []string{path}, nil
+	}
+
+	if input == "-" {
+		return readTargets(os.Stdin)
+	}
+
+	f, err := os.Open(input)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open input file: %w", err)
+	}
+	defer func(f io.Closer) {
+		_ = f.Close()
+	}(f)
+
+	return readTargets(f)
+}
+
+// readTargets reads page URLs line by line. Empty lines and lines starting
+// with # are skipped.
+func readTargets(r io.Reader) ([]string, error) {
+	var targets []string
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		targets = append(targets, line)
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read input: %w", err)
+	}
+
+	if len(targets) == 0 {
+		return nil, errors.New("no page URLs were provided")
+	}
+
+	return targets, nil
+}
+
+// printSummary prints combined results of all crawled targets.
+func printSummary(results []result) {
+	var found, failed, crashed int
+
+	fmt.Fprint(out, "\n>>> Summary:\n")
+	for _, r := range results {
+		status := "ok"
+		if r.err != nil {
+			status = "failed: " + r.err.Error()
+			crashed++
+		}
+		fmt.Fprintf(out, ">>> %s: %d links, %d errors, %s\n", r.target, r.found, r.failed, status)
+
+		found += r.found
+		failed += r.failed
+	}
+
+	fmt.Fprintf(
+		out, ">>> Total: %d targets (%d failed), %d links, %d errors\n",
+		len(results), crashed, found, failed,
+	)
+}
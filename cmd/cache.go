@@ -0,0 +1,52 @@
+package main
+
+import (
+	"io"
+	"log"
+	"time"
+
+	"reactor-crw"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	cacheDir   string
+	cacheTTL   time.Duration
+	cacheMedia bool
+	offline    bool
+	recordDir  string
+	replayDir  string
+)
+
+// addCacheFlags adds flags caching, recording and replaying responses to the
+// command.
+func addCacheFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&cacheDir, "cache-dir", "", "Cache crawled pages in the directory, so crawling them again doesn't make requests.\nPages aren't cached if it's empty")
+	cmd.Flags().DurationVar(&cacheTTL, "cache-ttl", time.Hour, "Time cached pages are used for. 0 means they never expire")
+	cmd.Flags().BoolVar(&cacheMedia, "cache-media", false, "Cache media content along with pages")
+	cmd.Flags().BoolVar(&offline, "offline", false, "Don't make requests and read all pages from the cache set with --cache-dir")
+	cmd.Flags().StringVar(&recordDir, "record", "", "Record all responses to the fixture archive in the directory")
+	cmd.Flags().StringVar(&replayDir, "replay", "", "Serve responses from the fixture archive in the directory instead of making requests")
+}
+
+// closeTransport closes the transport if it keeps data that must be written
+// at the end, like the index of recorded fixtures.
+func closeTransport(t reactor_crw.Transport) {
+	c, ok := t.(io.Closer)
+	if !ok {
+		return
+	}
+
+	if err := c.Close(); err != nil {
+		logger.Error("cannot close transport", "error", err)
+	}
+}
+
+// fatal closes the transport and exits with the error. It should be used
+// instead of log.Fatal once the transport is created, since log.Fatal skips the
+// deferred closeTransport.
+func fatal(t reactor_crw.Transport, err error) {
+	closeTransport(t)
+	log.Fatal(err)
+}
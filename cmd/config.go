@@ -0,0 +1,301 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"reactor-crw"
+	"reactor-crw/config"
+	"reactor-crw/cookies"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	configPath string
+
+	runCmd = &cobra.Command{
+		Use:   "run",
+		Short: "Run crawl jobs declared in the configuration file",
+		Long: "Runs all crawl jobs declared in the YAML configuration file one by one.\n" +
+			"Environment variables like ${VAR} are expanded within the file.\n" +
+			"Example: reactor-crw run --config jobs.yaml",
+		Run: runConfig,
+	}
+)
+
+func init() {
+	runCmd.Flags().StringVar(&configPath, "config", "", "Path to the configuration file")
+	_ = runCmd.MarkFlagRequired("config")
+	runCmd.Flags().StringVar(&events, "events", "", "Write crawl events to stdout in the given format instead of the progress bar.\nPossible values: json")
+	addMetricsFlag(runCmd)
+	addBandwidthFlags(runCmd)
+	addListFlags(runCmd)
+	addExportFlag(runCmd)
+
+	crawlerCmd.AddCommand(runCmd)
+}
+
+func runConfig(_ *cobra.Command, _ []string) {
+	start := time.Now()
+
+	c, err := config.Load(configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	l, err := eventListener()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	serveMetrics()
+
+	if err := configBandwidth(); err != nil {
+		log.Fatal(err)
+	}
+
+	jobs, err := configJobs(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	t, err := configTransport(c.Transport)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer closeTransport(t)
+
+	if exporting() {
+		if err = openExport(c.Transport); err != nil {
+			fatal(t, err)
+		}
+	}
+
+	if listOnly() {
+		listJobs(t, jobs)
+	} else {
+		runJobs(t, jobs, l)
+	}
+
+	if exporting() {
+		closeExport()
+	}
+
+	fmt.Fprintf(out, "\n>>> Done in %s\n", time.Since(start).String())
+}
+
+// configTransport creates a transport shared by all jobs of the configuration.
+// If the proxy pool is set, requests are distributed among its proxies. If the
+// cache directory is set, pages are cached. Responses are recorded to or
+// replayed from a fixture archive if its directory is set.
+func configTransport(c config.Transport) (reactor_crw.Transport, error) {
+	if c.Offline && c.CacheDir == "" {
+		return nil, fmt.Errorf("cache directory is required in the offline mode")
+	}
+
+	if c.ReplayDir != "" {
+		if c.RecordDir != "" {
+			return nil, fmt.Errorf("responses cannot be recorded and replayed at the same time")
+		}
+		return reactor_crw.NewReplayTransport(c.ReplayDir)
+	}
+
+	var t reactor_crw.Transport
+	if len(c.ProxyPool) > 0 {
+		pool, err := configProxyPool(c)
+		if err != nil {
+			return nil, err
+		}
+		t = pool
+	} else {
+		client, err := configClient(c)
+		if err != nil {
+			return nil, err
+		}
+		if t, err = newHttpTransport(client, c); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.RateLimit > 0 {
+		t = reactor_crw.NewThrottledTransport(t, c.RateLimit)
+	}
+
+	// The cache wraps the throttled transport, so cache hits aren't delayed.
+	if c.CacheDir != "" {
+		ct, err := reactor_crw.NewCachedTransport(t, c.CacheDir)
+		if err != nil {
+			return nil, err
+		}
+		ct.TTL = c.CacheTTL
+		ct.CacheMedia = c.CacheMedia
+		ct.Offline = c.Offline
+		ct.Logger = logger
+		t = ct
+	}
+
+	// The recorder wraps the cache, so cached pages are recorded as well.
+	if c.RecordDir != "" {
+		rt, err := reactor_crw.NewRecordingTransport(t, c.RecordDir)
+		if err != nil {
+			return nil, err
+		}
+		rt.Logger = logger
+		t = rt
+	}
+
+	return t, nil
+}
+
+// newHttpTransport creates an HTTP transport sending headers of the
+// configuration and reporting to the crawl metrics and the logger.
+func newHttpTransport(client *http.Client, c config.Transport) (*reactor_crw.HttpTransport, error) {
+	p, err := configProfile(c)
+	if err != nil {
+		return nil, err
+	}
+
+	t := reactor_crw.NewHttpTransport(client, p.Values().With(c.Headers))
+	t.Metrics = crawlMetrics
+	t.Logger = logger
+	t.HeaderRules = configHeaderRules(c)
+	t.StallTimeout = c.StallTimeout
+	if c.RotateUserAgent {
+		t.UserAgents = p.UserAgents
+	}
+
+	return t, nil
+}
+
+// configClient creates an HTTP client with proxies, timeouts and the cookie
+// jar of the configuration.
+func configClient(c config.Transport) (*http.Client, error) {
+	tr, err := configRoundTripper(c, configProxies(c))
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Transport: tr}
+
+	if c.CookieJar != "" || c.CookiesFile != "" {
+		jar, err := configCookieJar(c)
+		if err != nil {
+			return nil, err
+		}
+		client.Jar = jar
+	}
+
+	return client, nil
+}
+
+// configRoundTripper creates a transport of an HTTP client using the proxies
+// and timeouts of the configuration.
+func configRoundTripper(c config.Transport, p reactor_crw.Proxies) (*http.Transport, error) {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	if !p.Empty() {
+		proxy, err := reactor_crw.ProxyFunc(p)
+		if err != nil {
+			return nil, err
+		}
+		tr.Proxy = proxy
+	}
+
+	reactor_crw.Timeouts{
+		Connect:         c.ConnectTimeout,
+		TLSHandshake:    c.TLSHandshakeTimeout,
+		ResponseHeader:  c.ResponseHeaderTimeout,
+		MaxConnsPerHost: c.MaxConnsPerHost,
+	}.Apply(tr)
+
+	return tr, nil
+}
+
+// configCookieJar opens the cookie jar and adds imported cookies to it. If
+// the jar path isn't set, cookies are kept in memory.
+func configCookieJar(c config.Transport) (*cookies.Jar, error) {
+	jar := cookies.New("")
+	if c.CookieJar != "" {
+		var err error
+		if jar, err = cookies.Open(c.CookieJar); err != nil {
+			return nil, err
+		}
+	}
+	jar.Logger = logger
+
+	if c.CookiesFile != "" {
+		entries, err := cookies.ReadFile(c.CookiesFile)
+		if err != nil {
+			return nil, err
+		}
+		if err = jar.Add(entries); err != nil {
+			return nil, fmt.Errorf("cannot save cookie jar: %w", err)
+		}
+		logger.Info("cookies imported", "file", c.CookiesFile, "count", len(entries))
+	}
+
+	return jar, nil
+}
+
+// configJobs converts jobs declared in the configuration to crawl jobs.
+func configJobs(c *config.Config) ([]job, error) {
+	jobs := make([]job, 0, len(c.Jobs))
+
+	for _, cj := range c.Jobs {
+		folder, err := cj.FolderName()
+		if err != nil {
+			return nil, err
+		}
+
+		fileName, err := cj.FileNameTemplate()
+		if err != nil {
+			return nil, err
+		}
+
+		destination := cj.Destination
+		if destination == "" {
+			destination = savePath
+		}
+
+		rules, err := contentRules(cj.Filter)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter of %s: %w", cj.Name, err)
+		}
+
+		images, err := imageRules(cj.Filter)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter of %s: %w", cj.Name, err)
+		}
+		if !images.Empty() && (listOnly() || exporting()) {
+			logger.Warn("image filters are applied only to downloaded content", "job", cj.Name)
+		}
+
+		posts, err := postFilter(cj.Filter)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter of %s: %w", cj.Name, err)
+		}
+
+		jobs = append(jobs, job{
+			path:        cj.Path,
+			search:      strings.Join(cj.Search, ","),
+			destination: destination,
+			folder:      folder,
+			fileName:    fileName,
+			firstPage:   cj.Pages.From,
+			lastPage:    cj.Pages.To,
+			singlePage:  cj.SinglePage,
+			followPosts: cj.FollowPosts,
+			comments:    cj.Comments,
+			workers:     cj.Workers,
+			filter:      rules,
+			images:      images,
+			posts:       posts,
+
+			commentsFolder: cj.CommentsFolder,
+		})
+	}
+
+	return jobs, nil
+}
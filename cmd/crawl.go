@@ -0,0 +1,320 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"reactor-crw"
+	"reactor-crw/event"
+	"reactor-crw/handler"
+	"reactor-crw/handler/filter"
+	"reactor-crw/handler/fs"
+	"reactor-crw/parser"
+
+	"github.com/vbauerster/mpb/v7"
+	"github.com/vbauerster/mpb/v7/decor"
+)
+
+// defaultCommentsFolder is the subfolder for content from comments.
+const defaultCommentsFolder = "comments"
+
+// job contains everything required to crawl a single target.
+type job struct {
+	path        string
+	search      string
+	destination string
+	folder      string
+	fileName    *template.Template
+	firstPage   int
+	lastPage    int
+	singlePage  bool
+	followPosts bool
+	comments    bool
+	workers     int
+
+	// commentsFolder is the subfolder for content from comments. If it's
+	// empty such content is saved along with the rest.
+	commentsFolder string
+
+	// quiet disables rendering of the progress bar. It's used when the
+	// crawler isn't attached to a terminal.
+	quiet bool
+
+	// events receives events of the crawler and the client if it's set.
+	events event.Listener
+
+	// filter contains rules for content that should be saved.
+	filter filter.Rules
+
+	// images contains rules for images that should be saved. They're checked
+	// only when content is downloaded.
+	images filter.ImageRules
+
+	// posts filters posts whose content is collected.
+	posts reactor_crw.PostFilter
+}
+
+// newJob creates a job for the target using values of command line flags.
+func newJob(target string) job {
+	return job{
+		path:           target,
+		search:         search,
+		destination:    savePath,
+		singlePage:     singlePage,
+		followPosts:    followPost,
+		comments:       comments,
+		workers:        maxWorkers,
+		commentsFolder: commentsTo,
+	}
+}
+
+// runJobs crawls all jobs one by one using the same transport and the same
+// dedup index and prints a combined summary if there is more than one job.
+// If the listener is set, jobs send their events to it instead of rendering
+// the progress bar.
+func runJobs(t reactor_crw.Transport, jobs []job, l event.Listener) []result {
+	index := handler.NewIndex()
+	results := make([]result, 0, len(jobs))
+
+	for _, j := range jobs {
+		if l != nil {
+			j.events = l
+			j.quiet = true
+		}
+		if len(jobs) > 1 {
+			fmt.Fprintf(out, "\n>>> Crawling %s\n", j.path)
+		}
+		results = append(results, crawl(context.Background(), t, index, j))
+	}
+
+	if len(jobs) > 1 {
+		printSummary(results)
+	}
+
+	return results
+}
+
+// crawl runs the crawler client for a single job and reports its progress. The
+// crawl is stopped once the context is done.
+func crawl(ctx context.Context, t reactor_crw.Transport, index *handler.Index, j job) result {
+	res := result{target: j.path}
+
+	c, err := newClient(t, index, j)
+	if err != nil {
+		res.err = err
+		fmt.Fprintf(out, ">>> %s\n", res.err)
+		return res
+	}
+
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- c.RunContext(ctx, j.path, j.search)
+	}()
+
+	report := progress
+	if j.quiet {
+		report = collect
+	}
+
+	res.found, res.failed = report(c.TotalSources, c.Progress, c.Errors)
+
+	res.err = <-runErr
+	if res.err != nil && !errors.Is(res.err, context.Canceled) {
+		fmt.Fprintf(out, ">>> Cannot crawl %s: %s\n", j.path, res.err)
+	}
+
+	return res
+}
+
+// newClient creates a crawler client for the job. Content is saved to a
+// separate folder named after the job path unless the job sets its own folder.
+// If content is exported, it's written to the input file of the downloader
+// instead.
+func newClient(t reactor_crw.Transport, index *handler.Index, j job) (*reactor_crw.Client, error) {
+	folder := j.folder
+	if folder == "" {
+		pathUrl, err := url.Parse(j.path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path provided: %s", j.path)
+		}
+		folder = strings.Replace(pathUrl.Path, "/", "_", -1)
+	}
+
+	names := fs.NameResolver{
+		CommentsFolder: j.commentsFolder,
+		NameTemplate:   j.fileName,
+	}
+
+	var ch handler.ContentHandler
+	if exporting() {
+		ch = newExportHandler(names, filepath.Join(j.destination, folder))
+	} else {
+		pr, err := fs.NewPathResolver(j.destination)
+		if err != nil {
+			return nil, fmt.Errorf("cannot process provided destination: %s", j.destination)
+		}
+
+		fsh, err := fs.NewFileSaver(pr, t, folder)
+		if err != nil {
+			return nil, err
+		}
+		fsh.NameResolver = names
+		fsh.Logger = logger
+		fsh.Limiter = bandwidth
+		fsh.DownloadRate = downloadLimit
+		fsh.MinSize = j.filter.MinSize
+		fsh.MaxSize = j.filter.MaxSize
+
+		var inspectors fs.Inspectors
+		if len(j.filter.MIMETypes) > 0 {
+			inspectors = append(inspectors, &filter.Filter{Rules: j.filter, Logger: logger})
+		}
+		if !j.images.Empty() {
+			inspectors = append(inspectors, &filter.ImageFilter{Rules: j.images, Logger: logger})
+		}
+		if len(inspectors) > 0 {
+			fsh.Inspector = inspectors
+		}
+
+		ch = fsh
+	}
+
+	// Already seen sources are skipped before the filter, so they aren't
+	// probed again.
+	if f := contentFilter(t, j); f != nil {
+		ch = f.Wrap(ch)
+	}
+	ch = index.Wrap(ch)
+
+	c := reactor_crw.NewClient(newCrawler(t, j), j.workers, ch)
+	c.Events = j.events
+	c.Metrics = crawlMetrics
+	c.Logger = logger
+
+	return c, nil
+}
+
+// contentFilter creates a filter for the job rules. It returns nil if the job
+// has no rules.
+func contentFilter(t reactor_crw.Transport, j job) *filter.Filter {
+	if j.filter.Empty() {
+		return nil
+	}
+
+	f := &filter.Filter{Rules: j.filter, Logger: logger}
+	if p, ok := t.(reactor_crw.Prober); ok {
+		f.Prober = p
+	} else if j.filter.MinSize > 0 || j.filter.MaxSize > 0 || len(j.filter.MIMETypes) > 0 {
+		logger.Warn("transport doesn't support probing, size and type are checked only while downloading")
+	}
+
+	return f
+}
+
+// newCrawler creates a crawler for the job.
+func newCrawler(t reactor_crw.Transport, j job) *reactor_crw.HtmlCrawler {
+	return &reactor_crw.HtmlCrawler{
+		Transport:   t,
+		Parser:      &parser.Html{},
+		MultiPage:   !j.singlePage,
+		FirstPage:   j.firstPage,
+		LastPage:    j.lastPage,
+		FollowPosts: j.followPosts,
+		Comments:    j.comments,
+		MaxWorkers:  j.workers,
+		PostFilter:  j.posts,
+		Events:      j.events,
+		Metrics:     crawlMetrics,
+		Logger:      logger,
+	}
+}
+
+// collect consumes the progress of the crawler client without rendering it.
+// Errors are logged by the client itself. It returns the amount of found links
+// and the amount of links that failed to process.
+func collect(total, task <-chan int, err <-chan error) (int, int) {
+	t := <-total
+	failed := 0
+
+	for task != nil || err != nil {
+		select {
+		case _, ok := <-task:
+			if !ok {
+				task = nil
+			}
+		case e, ok := <-err:
+			if !ok {
+				err = nil
+				continue
+			}
+			if e != nil {
+				failed++
+			}
+		}
+	}
+
+	return t, failed
+}
+
+// progress renders the progress of the crawler client and returns the amount
+// of found links and the amount of links that failed to process. Log records
+// are held until the progress bar is finished.
+func progress(total, task <-chan int, err <-chan error) (int, int) {
+	fmt.Fprint(out, "\n>>> Trying to count the amount of links. Please wait...\n\n")
+
+	t := <-total
+	if t == 0 {
+		fmt.Fprint(out, ">>> No links were found. Stopping...\n")
+		return 0, 0
+	}
+	fmt.Fprintf(out, ">>> %d links were found. Start downloading\n\n", t)
+
+	prg := mpb.New(mpb.WithWidth(64), mpb.WithOutput(out))
+
+	name := ">>> Progress:"
+	bar := prg.AddBar(int64(t),
+		mpb.PrependDecorators(
+			decor.Name(name, decor.WC{W: len(name) + 1, C: decor.DidentRight}),
+			decor.CountersNoUnit("%d/%d", decor.WCSyncWidth),
+		),
+		mpb.AppendDecorators(decor.Percentage(decor.WC{W: 5})),
+	)
+
+	logs.hold()
+	defer logs.release()
+
+	failed := 0
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		for task != nil || err != nil {
+			select {
+			case _, ok := <-task:
+				if !ok {
+					task = nil
+					continue
+				}
+				bar.Increment()
+			case e, ok := <-err:
+				if !ok {
+					err = nil
+					continue
+				}
+				if e != nil {
+					failed++
+				}
+			}
+		}
+	}()
+
+	prg.Wait()
+	<-done
+
+	return t, failed
+}
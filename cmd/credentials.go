@@ -0,0 +1,317 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"reactor-crw/credentials"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// Environment variables providing credentials.
+const (
+	envCookie     = "REACTOR_CRW_COOKIE"
+	envPassword   = "REACTOR_CRW_PASSWORD"
+	envPassphrase = "REACTOR_CRW_PASSPHRASE"
+)
+
+var (
+	secretFile     string
+	credentialPath string
+
+	// store is opened by credentialStore once per command.
+	store *credentials.Store
+
+	// stdin is shared by all reads of secrets, so buffered input isn't lost
+	// between them.
+	stdin = bufio.NewReader(os.Stdin)
+
+	credentialsCmd = &cobra.Command{
+		Use:   "credentials",
+		Short: "Manage credentials kept in the encrypted credential store",
+		Long: "Keeps the cookie, the username and the password in a local file encrypted\n" +
+			"with a passphrase, so they don't end up in shell history or cron files.\n" +
+			"The passphrase is read from " + envPassphrase + " or asked in the terminal.\n" +
+			"Example: reactor-crw credentials set cookie",
+	}
+
+	credentialsSetCmd = &cobra.Command{
+		Use:       "set <cookie|username|password>",
+		Short:     "Set a credential. The value is read from stdin",
+		Args:      cobra.ExactValidArgs(1),
+		ValidArgs: []string{credentials.KeyCookie, credentials.KeyUsername, credentials.KeyPassword},
+		Run:       runCredentialsSet,
+	}
+
+	credentialsDeleteCmd = &cobra.Command{
+		Use:       "delete <cookie|username|password>",
+		Short:     "Delete a credential",
+		Args:      cobra.ExactValidArgs(1),
+		ValidArgs: []string{credentials.KeyCookie, credentials.KeyUsername, credentials.KeyPassword},
+		Run:       runCredentialsDelete,
+	}
+
+	credentialsListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List names of stored credentials",
+		Args:  cobra.NoArgs,
+		Run:   runCredentialsList,
+	}
+)
+
+func init() {
+	credentialsCmd.PersistentFlags().StringVar(&credentialPath, "credentials", "", "Encrypted credential store file (default "+defaultCredentials()+")")
+	credentialsCmd.AddCommand(credentialsSetCmd, credentialsDeleteCmd, credentialsListCmd)
+
+	crawlerCmd.AddCommand(credentialsCmd)
+}
+
+// addCredentialFlags adds flags of cookie sources to the command.
+func addCredentialFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&secretFile, "secret-file", "", "A file containing the user's cookie. It must not be accessible by other users")
+	addCredentialStoreFlag(cmd)
+}
+
+// addCredentialStoreFlag adds the flag setting the credential store file to
+// the command.
+func addCredentialStoreFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&credentialPath, "credentials", "", "Encrypted credential store file. It's used only if it exists (default "+defaultCredentials()+")")
+}
+
+// defaultCredentials returns the path of the credential store in the user's
+// config folder.
+func defaultCredentials() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "reactor-crw", "credentials.json")
+}
+
+// resolveCookie returns the user's cookie. Sources are checked in the
+// following order: the --cookie flag, the --secret-file flag, the
+// REACTOR_CRW_COOKIE environment variable and the credential store. An empty
+// string is returned if none of them provides the cookie.
+func resolveCookie() (string, error) {
+	if cookie != "" {
+		return cookie, nil
+	}
+
+	if secretFile != "" {
+		return credentials.ReadFile(secretFile)
+	}
+
+	if c := os.Getenv(envCookie); c != "" {
+		return c, nil
+	}
+
+	s, err := credentialStore()
+	if err != nil || s == nil {
+		return "", err
+	}
+
+	return s.Get(credentials.KeyCookie), nil
+}
+
+// resolveLogin returns the username and the password used by the login
+// command. The username is taken from the --username flag or the credential
+// store. The password is taken from the --password flag, the
+// REACTOR_CRW_PASSWORD environment variable or the credential store, and it's
+// asked interactively as the last resort.
+func resolveLogin() (string, string, error) {
+	user, pass := username, password
+	if pass == "" {
+		pass = os.Getenv(envPassword)
+	}
+
+	if user == "" || pass == "" {
+		s, err := credentialStore()
+		if err != nil {
+			return "", "", err
+		}
+		if s != nil && user == "" {
+			user = s.Get(credentials.KeyUsername)
+		}
+		if s != nil && pass == "" {
+			pass = s.Get(credentials.KeyPassword)
+		}
+	}
+
+	if user == "" {
+		return "", "", fmt.Errorf("username is required")
+	}
+
+	if pass == "" {
+		var err error
+		if pass, err = readSecret("Password: "); err != nil {
+			return "", "", err
+		}
+	}
+
+	return user, pass, nil
+}
+
+// errNoPassphrase is returned when the passphrase of the credential store is
+// neither set by the environment variable nor can be asked in the terminal.
+var errNoPassphrase = errors.New("credential store passphrase is required, set " + envPassphrase)
+
+// credentialStore opens the credential store. It returns nil if the store
+// file doesn't exist. The default store is skipped with a warning if the
+// passphrase isn't available, so unattended runs like cron jobs don't depend
+// on it. A store set by --credentials requires the passphrase.
+func credentialStore() (*credentials.Store, error) {
+	if store != nil {
+		return store, nil
+	}
+
+	explicit := credentialPath != ""
+	if _, err := os.Stat(storePath()); err != nil {
+		return nil, nil
+	}
+
+	s, err := openStore()
+	if errors.Is(err, errNoPassphrase) && !explicit {
+		logger.Warn("credential store skipped, its passphrase isn't available", "path", credentialPath)
+		return nil, nil
+	}
+
+	return s, err
+}
+
+// storePath returns the path of the credential store. The default path is
+// used unless --credentials is set.
+func storePath() string {
+	if credentialPath == "" {
+		credentialPath = defaultCredentials()
+	}
+
+	return credentialPath
+}
+
+// openStore opens the credential store creating it if it doesn't exist.
+func openStore() (*credentials.Store, error) {
+	if storePath() == "" {
+		return nil, errors.New("cannot resolve the credential store path, set --credentials")
+	}
+
+	passphrase, err := readPassphrase()
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := credentials.Open(credentialPath, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	store = s
+
+	return s, nil
+}
+
+// readPassphrase returns the passphrase of the credential store. It's taken
+// from the environment variable or asked in the terminal, never read from
+// piped stdin. The passphrase of a new store is asked twice.
+func readPassphrase() (string, error) {
+	if p := os.Getenv(envPassphrase); p != "" {
+		return p, nil
+	}
+
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", errNoPassphrase
+	}
+
+	p, err := readSecret("Passphrase for " + credentialPath + ": ")
+	if err != nil {
+		return "", err
+	}
+
+	if _, err = os.Stat(credentialPath); os.IsNotExist(err) {
+		confirm, err := readSecret("Repeat the passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if confirm != p {
+			return "", errors.New("passphrases don't match")
+		}
+	}
+
+	return p, nil
+}
+
+// readSecret reads a secret from stdin. If stdin is a terminal, the prompt is
+// printed and the input isn't echoed.
+func readSecret(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, prompt)
+		b, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("cannot read secret: %w", err)
+		}
+
+		return string(b), nil
+	}
+
+	line, err := stdin.ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("cannot read secret: %w", err)
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func runCredentialsSet(_ *cobra.Command, args []string) {
+	s, err := openStore()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	value, err := readSecret("Value of " + args[0] + ": ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if value == "" {
+		log.Fatal("empty value provided, use delete to remove the credential")
+	}
+
+	s.Set(args[0], value)
+	if err = s.Save(); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Fprintf(out, ">>> %s is saved to %s\n", args[0], credentialPath)
+}
+
+func runCredentialsDelete(_ *cobra.Command, args []string) {
+	s, err := openStore()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	s.Set(args[0], "")
+	if err = s.Save(); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Fprintf(out, ">>> %s is deleted from %s\n", args[0], credentialPath)
+}
+
+func runCredentialsList(_ *cobra.Command, _ []string) {
+	s, err := openStore()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, k := range s.Keys() {
+		fmt.Fprintln(out, k)
+	}
+}
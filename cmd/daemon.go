@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"syscall"
+	"time"
+
+	"reactor-crw"
+	"reactor-crw/config"
+	"reactor-crw/handler"
+
+	"github.com/robfig/cron/v3"
+	"github.com/spf13/cobra"
+)
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run crawl jobs periodically according to their schedules",
+	Long: "Runs crawl jobs declared in the YAML configuration file according to their\n" +
+		"cron schedules. Runs of the same job never overlap. If state_dir is set the\n" +
+		"already downloaded content is remembered between runs, so only new content\n" +
+		"is downloaded. SIGINT or SIGTERM cancels running jobs, saves their state and\n" +
+		"stops the daemon.\n" +
+		"Example: reactor-crw daemon --config jobs.yaml",
+	Run: runDaemon,
+}
+
+func init() {
+	daemonCmd.Flags().StringVar(&configPath, "config", "", "Path to the configuration file")
+	_ = daemonCmd.MarkFlagRequired("config")
+	addMetricsFlag(daemonCmd)
+	addBandwidthFlags(daemonCmd)
+
+	crawlerCmd.AddCommand(daemonCmd)
+}
+
+func runDaemon(_ *cobra.Command, _ []string) {
+	c, err := config.Load(configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	jobs, err := configJobs(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	serveMetrics()
+
+	if err := configBandwidth(); err != nil {
+		log.Fatal(err)
+	}
+
+	t, err := configTransport(c.Transport)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer closeTransport(t)
+	if c.StateDir != "" {
+		if err := os.MkdirAll(c.StateDir, 0700); err != nil {
+			fatal(t, fmt.Errorf("cannot create state directory: %w", err))
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	sched := cron.New(cron.WithChain(cron.SkipIfStillRunning(cron.DefaultLogger)))
+	stateFiles := make(map[string]string, len(c.Jobs))
+
+	for i, cj := range c.Jobs {
+		if cj.Schedule == "" {
+			fatal(t, fmt.Errorf("job %s has no schedule", cj.Name))
+		}
+
+		j := jobs[i]
+		j.quiet = true
+
+		statePath := ""
+		if c.StateDir != "" {
+			file := stateFileName(cj.Name)
+			if other, ok := stateFiles[file]; ok {
+				fatal(t, fmt.Errorf("jobs %s and %s share the state file %s, rename one of them", other, cj.Name, file))
+			}
+			stateFiles[file] = cj.Name
+			statePath = filepath.Join(c.StateDir, file)
+		}
+
+		name := cj.Name
+		_, err = sched.AddFunc(cj.Schedule, func() {
+			runScheduled(ctx, t, name, j, statePath)
+		})
+		if err != nil {
+			fatal(t, fmt.Errorf("cannot schedule job %s: %w", cj.Name, err))
+		}
+	}
+
+	sched.Start()
+	logger.Info("daemon started", "jobs", len(jobs))
+
+	<-ctx.Done()
+
+	logger.Info("shutting down, cancelling running jobs")
+	<-sched.Stop().Done()
+	logger.Info("daemon stopped")
+}
+
+// runScheduled runs a single scheduled job until it's finished or the context
+// is done. If statePath is set the index of downloaded content is loaded before
+// the run and saved after it, even if the run is cancelled.
+func runScheduled(ctx context.Context, t reactor_crw.Transport, name string, j job, statePath string) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	index := handler.NewIndex()
+	if statePath != "" {
+		var err error
+		index, err = handler.LoadIndex(statePath)
+		if err != nil {
+			logger.Error("job skipped", "job", name, "error", err)
+			return
+		}
+	}
+
+	if statePath != "" {
+		defer func() {
+			if err := index.Save(statePath); err != nil {
+				logger.Error("cannot save job state", "job", name, "error", err)
+			}
+		}()
+	}
+
+	start := time.Now()
+	logger.Info("job started", "job", name)
+
+	res := crawl(ctx, t, index, j)
+
+	if errors.Is(res.err, context.Canceled) {
+		logger.Info("job cancelled", "job", name, "duration", time.Since(start), "found", res.found, "failed", res.failed)
+		return
+	}
+	if res.err != nil {
+		logger.Error("job failed", "job", name, "duration", time.Since(start), "error", res.err)
+		return
+	}
+
+	logger.Info("job finished", "job", name, "duration", time.Since(start), "found", res.found, "failed", res.failed)
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// stateFileName converts the job name to a safe name of its state file.
+func stateFileName(name string) string {
+	return unsafeFileChars.ReplaceAllString(name, "_") + ".json"
+}
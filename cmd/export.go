@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"reactor-crw"
+	"reactor-crw/config"
+	"reactor-crw/handler/export"
+	"reactor-crw/handler/fs"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportTool string
+
+	// exportFile receives input files written by export handlers of all
+	// jobs. exportHeaders are the headers of the transport written along with
+	// each link.
+	exportFile    io.WriteCloser
+	exportHeaders reactor_crw.Headers
+)
+
+// addExportFlag adds the flag enabling export of found links to the command.
+func addExportFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&exportTool, "export", "", "Write found links to an input file of another downloader set with --output instead\nof downloading them. Possible values: aria2,wget")
+}
+
+// exporting reports whether found links should be exported instead of
+// downloaded.
+func exporting() bool {
+	return exportTool != ""
+}
+
+// openExport creates the input file set with --output. Headers of the
+// transport are written along with each link if the downloader supports it.
+func openExport(c config.Transport) error {
+	tool, err := export.ParseTool(exportTool)
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		return errors.New("--output is required for --export")
+	}
+
+	headers, err := configHeaders(c)
+	if err != nil {
+		return err
+	}
+
+	// Headers are written to aria2 input files only. The file is readable
+	// only by its owner if they contain a session.
+	perm := os.FileMode(0644)
+	if tool == export.Aria2 && secretHeaders(headers) {
+		perm = 0600
+	}
+
+	f, err := os.OpenFile(output, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, perm)
+	if err != nil {
+		return fmt.Errorf("cannot create output file: %w", err)
+	}
+	// The mode of an existing file isn't changed by OpenFile.
+	if err = f.Chmod(perm); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("cannot create output file: %w", err)
+	}
+
+	exportFile = f
+	exportHeaders = headers
+
+	return nil
+}
+
+// secretHeaders reports whether the headers contain credentials of the user.
+func secretHeaders(h reactor_crw.Headers) bool {
+	for k, v := range h {
+		if v == "" {
+			continue
+		}
+		switch strings.ToLower(k) {
+		case "cookie", "authorization":
+			return true
+		}
+	}
+
+	return false
+}
+
+// closeExport closes the input file and reports where it was written.
+func closeExport() {
+	if err := exportFile.Close(); err != nil {
+		fmt.Fprintf(out, ">>> Cannot write %s: %s\n", output, err)
+		return
+	}
+
+	fmt.Fprintf(out, ">>> Links were exported to %s\n", output)
+}
+
+// newExportHandler creates a handler writing links to exportFile. Content
+// should be saved to the dir by the downloader.
+func newExportHandler(names fs.NameResolver, dir string) *export.Handler {
+	tool, _ := export.ParseTool(exportTool)
+
+	h := export.NewHandler(exportFile, tool)
+	h.NameResolver = names
+	h.Dir = dir
+	h.Headers = exportHeaders
+
+	return h
+}
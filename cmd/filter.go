@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"reactor-crw"
+	"reactor-crw/config"
+	"reactor-crw/handler/filter"
+
+	"github.com/spf13/cobra"
+)
+
+// filters contains rules of content filters provided with flags.
+var filters config.Filter
+
+// dateLayout is the layout of dates of post filters.
+const dateLayout = "2006-01-02"
+
+// addFilterFlags adds flags of content filters to the command.
+func addFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&filters.MinSize, "min-size", "", "Skip content smaller than the size. Example: 300K, 1.5MB")
+	cmd.Flags().StringVar(&filters.MaxSize, "max-size", "", "Skip content bigger than the size. Example: 20MB, 1G")
+	cmd.Flags().StringSliceVar(&filters.Extensions, "ext", nil, "A comma separated list of allowed extensions of content links. Example: --ext jpg,png")
+	cmd.Flags().BoolVar(&filters.ExcludeComments, "exclude-comments", false, "Skip content found in post comments")
+	cmd.Flags().StringSliceVar(&filters.MIMETypes, "mime", nil, "A comma separated list of allowed MIME types of content. Example: --mime \"image/*,video/mp4\".\nSize and MIME type are checked with a HEAD request before downloading")
+	cmd.Flags().IntVar(&filters.MinWidth, "min-width", 0, "Skip images narrower than the width in pixels")
+	cmd.Flags().IntVar(&filters.MinHeight, "min-height", 0, "Skip images lower than the height in pixels")
+	cmd.Flags().StringVar(&filters.Aspect, "aspect", "", "Skip images with another aspect ratio. Example: 16:9, 1.5.\nImage filters check the image header while it's downloaded")
+	cmd.Flags().StringSliceVar(&filters.ExcludeTags, "exclude-tags", nil, "A comma separated list of tags. Posts with any of them are skipped. Example: --exclude-tags \"politics,spam\"")
+	cmd.Flags().StringSliceVar(&filters.RequireTags, "require-tags", nil, "A comma separated list of tags. Posts without all of them are skipped")
+	cmd.Flags().Float64Var(&filters.MinRating, "min-rating", 0, "Skip posts with a lower rating")
+	cmd.Flags().StringVar(&filters.Since, "since", "", "Skip posts published before the date and stop crawling pages with older posts. Example: 2024-03-01.\nPost filters are applied before content of posts is collected")
+	cmd.Flags().StringVar(&filters.Until, "until", "", "Skip posts published after the date. Example: 2024-03-31")
+}
+
+// contentRules creates content filter rules from the filter values.
+func contentRules(f config.Filter) (filter.Rules, error) {
+	r := filter.Rules{
+		Extensions:      f.Extensions,
+		MIMETypes:       f.MIMETypes,
+		ExcludeComments: f.ExcludeComments,
+	}
+
+	var err error
+	if f.MinSize != "" {
+		if r.MinSize, err = filter.ParseSize(f.MinSize); err != nil {
+			return filter.Rules{}, err
+		}
+	}
+	if f.MaxSize != "" {
+		if r.MaxSize, err = filter.ParseSize(f.MaxSize); err != nil {
+			return filter.Rules{}, err
+		}
+	}
+
+	return r, nil
+}
+
+// imageRules creates image filter rules from the filter values.
+func imageRules(f config.Filter) (filter.ImageRules, error) {
+	r := filter.ImageRules{
+		MinWidth:  f.MinWidth,
+		MinHeight: f.MinHeight,
+	}
+
+	if f.Aspect != "" {
+		var err error
+		if r.Aspect, err = filter.ParseAspect(f.Aspect); err != nil {
+			return filter.ImageRules{}, err
+		}
+	}
+
+	return r, nil
+}
+
+// postFilter creates a post filter from the filter values.
+func postFilter(f config.Filter) (reactor_crw.PostFilter, error) {
+	pf := reactor_crw.PostFilter{
+		ExcludeTags: f.ExcludeTags,
+		RequireTags: f.RequireTags,
+		MinRating:   f.MinRating,
+	}
+
+	var err error
+	if f.Since != "" {
+		if pf.Since, err = parseDate(f.Since); err != nil {
+			return reactor_crw.PostFilter{}, err
+		}
+	}
+	if f.Until != "" {
+		if pf.Until, err = parseDate(f.Until); err != nil {
+			return reactor_crw.PostFilter{}, err
+		}
+		// Posts published during the day are included.
+		pf.Until = pf.Until.AddDate(0, 0, 1)
+	}
+	if !pf.Since.IsZero() && !pf.Until.IsZero() && !pf.Since.Before(pf.Until) {
+		return reactor_crw.PostFilter{}, fmt.Errorf("since date %s is after until date %s", f.Since, f.Until)
+	}
+
+	return pf, nil
+}
+
+// parseDate parses the date in the local time zone.
+func parseDate(s string) (time.Time, error) {
+	t, err := time.ParseInLocation(dateLayout, s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date: %s", s)
+	}
+
+	return t, nil
+}
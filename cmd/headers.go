@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"reactor-crw"
+	"reactor-crw/config"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	profile         string
+	rotateUserAgent bool
+	headerRules     []string
+)
+
+// addHeaderFlags adds flags setting request headers to the command.
+func addHeaderFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&profile, "profile", reactor_crw.DefaultProfile, "Header profile imitating a browser. Possible values: "+strings.Join(reactor_crw.ProfileNames(), ","))
+	cmd.Flags().BoolVar(&rotateUserAgent, "rotate-user-agent", false, "Send a random User-Agent of the profile with each request")
+	cmd.Flags().StringArrayVar(&headerRules, "header-rule", nil, "Header for a comma separated list of hosts, can be repeated.\nExample: --header-rule \"*.joyreactor.cc=Referer: http://joyreactor.cc/\"")
+}
+
+// headerConfig converts the header rule flag values to header settings.
+func headerConfig() ([]config.HeaderRule, error) {
+	rules := make([]config.HeaderRule, 0, len(headerRules))
+	for _, r := range headerRules {
+		parts := strings.SplitN(r, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid header rule %q, expected hosts=name: value", r)
+		}
+		header := strings.SplitN(parts[1], ":", 2)
+		if len(header) != 2 || strings.TrimSpace(header[0]) == "" {
+			return nil, fmt.Errorf("invalid header rule %q, expected hosts=name: value", r)
+		}
+		rules = append(rules, config.HeaderRule{
+			Hosts:   strings.Split(parts[0], ","),
+			Headers: map[string]string{strings.TrimSpace(header[0]): strings.TrimSpace(header[1])},
+		})
+	}
+
+	return rules, nil
+}
+
+// configProfile returns the header profile of the configuration.
+func configProfile(c config.Transport) (reactor_crw.HeaderProfile, error) {
+	if c.Profile == "" {
+		return reactor_crw.Profile(reactor_crw.DefaultProfile)
+	}
+
+	return reactor_crw.Profile(c.Profile)
+}
+
+// configHeaders returns headers of the profile overridden by custom headers
+// of the configuration.
+func configHeaders(c config.Transport) (reactor_crw.Headers, error) {
+	p, err := configProfile(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.Values().With(c.Headers), nil
+}
+
+// configHeaderRules converts header rules of the configuration.
+func configHeaderRules(c config.Transport) []reactor_crw.HeaderRule {
+	rules := make([]reactor_crw.HeaderRule, 0, len(c.HeaderRules))
+	for _, r := range c.HeaderRules {
+		rules = append(rules, reactor_crw.HeaderRule{Hosts: r.Hosts, Headers: r.Headers})
+	}
+
+	return rules
+}
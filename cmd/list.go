@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"reactor-crw"
+	"reactor-crw/handler"
+	"reactor-crw/handler/export"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	dryRun bool
+	output string
+)
+
+// addListFlags adds flags of the list-only mode to the command.
+func addListFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only crawl pages and print found links with their content type and page.\nNothing is downloaded and no folders are created")
+	cmd.Flags().StringVar(&output, "output", "", "Write found links to the file instead of printing them. The format is chosen\nby the extension: .txt (one URL per line), .json or .csv. Implies --dry-run\nunless --export is set")
+}
+
+// listOnly reports whether found links should be listed instead of downloaded.
+// The output file is used by the export instead if it's enabled.
+func listOnly() bool {
+	return (dryRun || output != "") && !exporting()
+}
+
+// listJobs crawls all jobs without downloading anything and writes the found
+// links to the output file or prints them. Links found by multiple jobs are
+// listed once. Links that don't match content filters are skipped.
+func listJobs(t reactor_crw.Transport, jobs []job) []result {
+	var (
+		sources []handler.Source
+		seen    = make(map[string]struct{})
+		results = make([]result, 0, len(jobs))
+	)
+
+	for _, j := range jobs {
+		res := result{target: j.path}
+
+		found, err := newCrawler(t, j).Fetch(j.path, strings.Split(j.search, ","))
+		if err != nil {
+			res.err = err
+			fmt.Fprintf(out, ">>> Cannot crawl %s: %s\n", j.path, err)
+		}
+
+		f := contentFilter(t, j)
+		for _, s := range found {
+			if _, ok := seen[s.URL]; ok {
+				continue
+			}
+			if f != nil && f.Check(s) != "" {
+				continue
+			}
+			seen[s.URL] = struct{}{}
+			sources = append(sources, s)
+		}
+
+		res.found = len(found)
+		results = append(results, res)
+	}
+
+	if err := writeSources(sources); err != nil {
+		fmt.Fprintf(out, ">>> %s\n", err)
+	}
+
+	if len(jobs) > 1 {
+		printSummary(results)
+	}
+
+	return results
+}
+
+// writeSources writes sources to the output file in the format chosen by its
+// extension or prints them as a table if the output isn't set.
+func writeSources(sources []handler.Source) error {
+	if output == "" {
+		tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "TYPE\tURL\tPAGE")
+		for _, s := range sources {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", s.Type, s.URL, s.Page)
+		}
+
+		return tw.Flush()
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("cannot create output file: %w", err)
+	}
+
+	err = export.WriteList(f, sources, export.FormatOf(output))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, ">>> %d links were written to %s\n", len(sources), output)
+
+	return nil
+}
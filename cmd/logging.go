@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync"
+
+	"reactor-crw/logging"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	logLevel  string
+	logFormat string
+
+	// logger is shared by all components of the command. It's created by
+	// setupLogger before the command runs.
+	logger = logging.Discard
+
+	// logs receives the log output. It's held while the progress bar is
+	// rendered, so log records don't break it.
+	logs = &heldWriter{w: os.Stderr}
+)
+
+func init() {
+	crawlerCmd.PersistentFlags().StringVar(&logLevel, "log-level", "", "Minimal level of log records written to stderr. Possible values: debug,info,warn,error.\nDefault value is warn when the progress bar is rendered and info otherwise")
+	crawlerCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "Format of log records. Possible values: text,json")
+	crawlerCmd.PersistentPreRunE = setupLogger
+}
+
+// setupLogger creates the logger according to the log flags.
+func setupLogger(cmd *cobra.Command, _ []string) error {
+	level := logging.LevelInfo
+	if (cmd == crawlerCmd || cmd == runCmd) && events == "" {
+		level = logging.LevelWarn
+	}
+
+	if logLevel != "" {
+		var err error
+		level, err = logging.ParseLevel(logLevel)
+		if err != nil {
+			return err
+		}
+	}
+
+	format, err := logging.ParseFormat(logFormat)
+	if err != nil {
+		return err
+	}
+
+	logger = logging.New(logs, format, level)
+
+	return nil
+}
+
+// heldWriter writes to w unless it's held. Writes made while it's held are
+// buffered and written on release.
+type heldWriter struct {
+	mu   sync.Mutex
+	w    io.Writer
+	held bool
+	buf  bytes.Buffer
+}
+
+func (h *heldWriter) Write(p []byte) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.held {
+		return h.buf.Write(p)
+	}
+
+	return h.w.Write(p)
+}
+
+func (h *heldWriter) hold() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.held = true
+}
+
+func (h *heldWriter) release() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.held = false
+	_, _ = h.buf.WriteTo(h.w)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"reactor-crw"
+	"reactor-crw/config"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	cookieJar   string
+	cookiesFile string
+	username    string
+	password    string
+	site        string
+
+	loginCmd = &cobra.Command{
+		Use:   "login",
+		Short: "Sign in to the site and keep the session in the cookie jar",
+		Long: "Signs in to the site using its login form and saves the session to the cookie\n" +
+			"jar file. Other commands load the jar and keep it up to date, so the cookie\n" +
+			"doesn't have to be copied from a browser. The username and the password are\n" +
+			"taken from flags, " + envPassword + " or the credential store, otherwise the\n" +
+			"password is asked interactively.\n" +
+			"Example: reactor-crw login -u username",
+		Run: runLogin,
+	}
+)
+
+func init() {
+	loginCmd.Flags().StringVarP(&username, "username", "u", "", "Username. It's taken from the credential store if it's empty")
+	loginCmd.Flags().StringVar(&password, "password", "", "Password. Prefer "+envPassword+" or the credential store since flags are kept in shell history")
+	loginCmd.Flags().StringVar(&site, "site", "http://joyreactor.cc", "Site to sign in to")
+	addCookieJarFlag(loginCmd)
+	addCredentialStoreFlag(loginCmd)
+	addProxyFlags(loginCmd)
+	addTimeoutFlags(loginCmd)
+
+	crawlerCmd.AddCommand(loginCmd)
+}
+
+// addCookieJarFlag adds the flag setting the cookie jar file to the command.
+func addCookieJarFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&cookieJar, "cookie-jar", defaultCookieJar(), "Cookie jar file created by the login command. Cookies set by the site are saved to it as well")
+}
+
+// addCookiesFileFlag adds the flag importing cookies from a browser to the
+// command.
+func addCookiesFileFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&cookiesFile, "cookies-file", "", "Cookies exported from a browser in the Netscape cookies.txt or JSON format.\nThey're sent according to their domain and path and saved to the cookie jar if it's used")
+}
+
+// defaultCookieJar returns the path of the cookie jar in the user's config
+// folder.
+func defaultCookieJar() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "reactor-crw", "cookies.json")
+}
+
+// cookieJarPath returns the path of the cookie jar used by the command or an
+// empty string if cookies shouldn't be kept. The default jar is used only if
+// it exists, so it's created by the login command only.
+func cookieJarPath(cmd *cobra.Command) string {
+	if cookieJar == "" || cmd.Flags().Changed("cookie-jar") {
+		return cookieJar
+	}
+
+	if _, err := os.Stat(cookieJar); err != nil {
+		return ""
+	}
+
+	return cookieJar
+}
+
+func runLogin(_ *cobra.Command, _ []string) {
+	if cookieJar == "" {
+		log.Fatal("cookie jar path is required")
+	}
+
+	user, pass, err := resolveLogin()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	proxies, err := proxyConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	client, err := configClient(config.Transport{
+		CookieJar:             cookieJar,
+		Proxy:                 proxy,
+		ProxyRules:            proxies,
+		ConnectTimeout:        connectTimeout,
+		TLSHandshakeTimeout:   tlsHandshakeTimeout,
+		ResponseHeaderTimeout: responseHeaderTimeout,
+		MaxConnsPerHost:       maxConnsPerHost,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	t := reactor_crw.NewHttpTransport(client, nil)
+	t.Logger = logger
+	t.StallTimeout = stallTimeout
+
+	if err = t.Login(site, user, pass); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Fprintf(out, ">>> Logged in as %s. The session is saved to %s\n", user, cookieJar)
+}
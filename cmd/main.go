@@ -2,19 +2,14 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"log"
-	"net/http"
-	"net/url"
 	"os"
-	"strings"
 	"time"
 
 	"reactor-crw"
-	"reactor-crw/handler/fs"
-	"reactor-crw/parser"
-
-	"github.com/vbauerster/mpb/v7"
-	"github.com/vbauerster/mpb/v7/decor"
+	"reactor-crw/config"
+	"reactor-crw/event"
 
 	"github.com/spf13/cobra"
 )
@@ -22,10 +17,20 @@ import (
 var (
 	search     string
 	path       string
+	input      string
 	savePath   string
 	cookie     string
 	maxWorkers int
+	rateLimit  float64
 	singlePage bool
+	followPost bool
+	comments   bool
+	commentsTo string
+	events     string
+
+	// out receives human readable output. It's switched to stderr when
+	// events are written to stdout.
+	out io.Writer = os.Stdout
 
 	crawlerCmd = &cobra.Command{
 		Use:   "reactor-crw",
@@ -42,92 +47,153 @@ func init() {
 
 	crawlerCmd.Flags().StringVarP(&search, "search", "s", "image,gif", "A comma separated list of content types that should be downloaded.\nPossible values: image,gif,webm,mp4. Example: -s \"image,webm\"")
 	crawlerCmd.Flags().StringVarP(&path, "path", "p", "", "Provide a full page URL")
+	crawlerCmd.Flags().StringVarP(&input, "input", "i", "", "A file with a list of page URLs to crawl, one per line. Use \"-\" to read\nthe list from stdin. Each page is saved to its own folder")
 	crawlerCmd.Flags().StringVarP(&savePath, "destination", "d", hd, "Save path for content. Default value is a user's home folder \n(example C:\\Users\\username for Windows)")
-	crawlerCmd.Flags().StringVarP(&cookie, "cookie", "c", "", "User's cookie. Some content may be unavailable without it")
+	crawlerCmd.Flags().StringVarP(&cookie, "cookie", "c", "", "User's cookie. Some content may be unavailable without it.\nPrefer --secret-file, "+envCookie+" or the credential store since flags are kept in shell history")
 	crawlerCmd.Flags().IntVarP(&maxWorkers, "workers", "w", 1, "Amount of workers")
+	crawlerCmd.Flags().Float64VarP(&rateLimit, "rate-limit", "r", 0, "Maximum amount of requests per second shared by all workers. 0 means no limit")
 	crawlerCmd.Flags().BoolVarP(&singlePage, "single-page", "o", false, "Crawl only one page")
+	crawlerCmd.Flags().BoolVarP(&followPost, "follow-posts", "f", false, "Collect post links from listing pages and crawl every post page.\nAllows to get full content of truncated or collapsed posts")
+	crawlerCmd.Flags().BoolVarP(&comments, "comments", "m", false, "Crawl comments of every post as well. Content from comments is saved\nto the subfolder set with --comments-folder. Implies -f")
+	crawlerCmd.Flags().StringVar(&commentsTo, "comments-folder", defaultCommentsFolder, "Subfolder for content from comments. Content is saved along with the rest if it's empty")
+	crawlerCmd.Flags().StringVar(&events, "events", "", "Write crawl events to stdout in the given format instead of the progress bar.\nPossible values: json")
+	addMetricsFlag(crawlerCmd)
+	addBandwidthFlags(crawlerCmd)
+	addCacheFlags(crawlerCmd)
+	addListFlags(crawlerCmd)
+	addExportFlag(crawlerCmd)
+	addCookieJarFlag(crawlerCmd)
+	addCookiesFileFlag(crawlerCmd)
+	addCredentialFlags(crawlerCmd)
+	addProxyFlags(crawlerCmd)
+	addTimeoutFlags(crawlerCmd)
+	addProxyPoolFlags(crawlerCmd)
+	addHeaderFlags(crawlerCmd)
+	addFilterFlags(crawlerCmd)
+}
 
-	_ = crawlerCmd.MarkFlagRequired("path")
+// eventListener creates a listener according to the --events flag. Human
+// readable output is moved to stderr when events are written to stdout.
+func eventListener() (event.Listener, error) {
+	switch events {
+	case "":
+		return nil, nil
+	case "json":
+		out = os.Stderr
+		return event.NewJSONWriter(os.Stdout), nil
+	default:
+		return nil, fmt.Errorf("unknown events format: %s", events)
+	}
 }
 
-func run(_ *cobra.Command, _ []string) {
+func run(cmd *cobra.Command, _ []string) {
 	start := time.Now()
-	t := reactor_crw.NewHttpTransport(&http.Client{}, reactor_crw.Headers{"Cookie": cookie})
 
-	pathUrl, err := url.Parse(path)
+	targets, err := resolveTargets()
 	if err != nil {
-		log.Fatalf("invalid path provided: %s", path)
+		log.Fatal(err)
 	}
 
-	pr, err := fs.NewPathResolver(savePath)
+	l, err := eventListener()
 	if err != nil {
-		log.Fatalf("cannot process provided destination: %s", savePath)
-	}
-
-	ch, _ := fs.NewFileSaver(pr, t, strings.Replace(pathUrl.Path, "/", "_", -1))
-	c := reactor_crw.NewClient(
-		&reactor_crw.HtmlCrawler{
-			Transport: t,
-			Parser:    &parser.Html{},
-			MultiPage: !singlePage,
-		},
-		maxWorkers,
-		ch,
-	)
-
-	go func() {
-		err = c.Run(path, search)
-		if err != nil {
-			log.Fatal(err)
-		}
-	}()
+		log.Fatal(err)
+	}
 
-	progress(c.TotalSources, c.Progress, c.Errors)
+	rules, err := contentRules(filters)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	fmt.Printf("\n>>> Done in %s\n", time.Since(start).String())
-}
+	images, err := imageRules(filters)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !images.Empty() && (listOnly() || exporting()) {
+		logger.Warn("image filters are applied only to downloaded content")
+	}
+
+	posts, err := postFilter(filters)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	serveMetrics()
+
+	if err := configBandwidth(); err != nil {
+		log.Fatal(err)
+	}
+
+	c, err := resolveCookie()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	proxies, err := proxyConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
 
-func progress(total, task <-chan int, err <-chan error) {
-	fmt.Print("\n>>> Trying to count the amount of links. Please wait...\n\n")
-
-	t := <-total
-	if t == 0 {
-		fmt.Print(">>> No links were found. Stopping...\n")
-		return
-	}
-	fmt.Printf(">>> %d links were found. Start downloading\n\n", t)
-
-	prg := mpb.New(mpb.WithWidth(64))
-
-	name := ">>> Progress:"
-	bar := prg.AddBar(int64(t),
-		mpb.PrependDecorators(
-			decor.Name(name, decor.WC{W: len(name) + 1, C: decor.DidentRight}),
-			decor.CountersNoUnit("%d/%d", decor.WCSyncWidth),
-		),
-		mpb.AppendDecorators(decor.Percentage(decor.WC{W: 5})),
-	)
-
-	errBuf := strings.Builder{}
-
-	go func() {
-		for {
-			select {
-			case <-task:
-				bar.Increment()
-			case e := <-err:
-				if e != nil {
-					errBuf.WriteString(e.Error() + "\n")
-				}
-			}
+	headers, err := headerConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	tc := config.Transport{
+		Headers:               reactor_crw.Headers{"Cookie": c},
+		RateLimit:             rateLimit,
+		CookieJar:             cookieJarPath(cmd),
+		CookiesFile:           cookiesFile,
+		Proxy:                 proxy,
+		ProxyRules:            proxies,
+		ProxyPool:             proxyPool,
+		ProxyStrategy:         proxyStrategy,
+		Profile:               profile,
+		RotateUserAgent:       rotateUserAgent,
+		HeaderRules:           headers,
+		ConnectTimeout:        connectTimeout,
+		TLSHandshakeTimeout:   tlsHandshakeTimeout,
+		ResponseHeaderTimeout: responseHeaderTimeout,
+		StallTimeout:          stallTimeout,
+		MaxConnsPerHost:       maxConnsPerHost,
+		CacheDir:              cacheDir,
+		CacheTTL:              cacheTTL,
+		CacheMedia:            cacheMedia,
+		Offline:               offline,
+		RecordDir:             recordDir,
+		ReplayDir:             replayDir,
+	}
+	t, err := configTransport(tc)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer closeTransport(t)
+
+	if exporting() {
+		if err = openExport(tc); err != nil {
+			fatal(t, err)
 		}
-	}()
+	}
 
-	prg.Wait()
+	jobs := make([]job, 0, len(targets))
+	for _, target := range targets {
+		j := newJob(target)
+		j.filter = rules
+		j.images = images
+		j.posts = posts
+		jobs = append(jobs, j)
+	}
 
-	if errBuf.Len() != 0 {
-		fmt.Printf(">>> Errors during crawler work:\n%s", errBuf.String())
+	if listOnly() {
+		listJobs(t, jobs)
+	} else {
+		runJobs(t, jobs, l)
 	}
+
+	if exporting() {
+		closeExport()
+	}
+
+	fmt.Fprintf(out, "\n>>> Done in %s\n", time.Since(start).String())
 }
 
 func main() {
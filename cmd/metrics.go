@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"reactor-crw/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/spf13/cobra"
+)
+
+const metricsPath = "/metrics"
+
+var (
+	metricsAddr string
+
+	// crawlMetrics is shared by the transport and all clients. It stays nil
+	// unless metrics are enabled, so nothing is recorded.
+	crawlMetrics *metrics.Metrics
+)
+
+// addMetricsFlag adds the flag enabling the metrics endpoint to the command.
+func addMetricsFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&metricsAddr, "metrics-addr", "", "Address for the Prometheus metrics endpoint, example: :9090.\nMetrics are disabled if it's empty")
+}
+
+// enableMetrics creates crawlMetrics along with Go runtime and process metrics
+// and returns the handler exposing them.
+func enableMetrics() http.Handler {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	crawlMetrics = metrics.New(reg)
+
+	return promhttp.HandlerFor(reg, promhttp.HandlerOpts{})
+}
+
+// serveMetrics serves metrics on metricsAddr in the background if the address
+// is set.
+func serveMetrics() {
+	if metricsAddr == "" {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(metricsPath, enableMetrics())
+
+	go func() {
+		err := http.ListenAndServe(metricsAddr, mux)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("metrics endpoint stopped", "error", err)
+		}
+	}()
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"reactor-crw"
+	"reactor-crw/config"
+	"reactor-crw/event"
+	"reactor-crw/handler"
+	"reactor-crw/server"
+
+	"github.com/spf13/cobra"
+)
+
+// envToken is the environment variable providing the API token of the server.
+const envToken = "REACTOR_CRW_TOKEN"
+
+var (
+	serveAddr       string
+	serveToken      string
+	serveMaxWorkers int
+	serveMaxJobs    int
+
+	serveCmd = &cobra.Command{
+		Use:   "serve",
+		Short: "Run HTTP server with REST API for submitting and monitoring crawls",
+		Long: "Runs HTTP server with REST API for submitting crawl jobs, listing them,\n" +
+			"viewing their progress and errors and cancelling them. Prometheus metrics\n" +
+			"are exposed on /metrics. If a token is set, all requests must have it in the\n" +
+			"Authorization: Bearer header. The token is required unless the server listens\n" +
+			"on a loopback address. Only joyreactor sites can be crawled. SIGINT or SIGTERM\n" +
+			"cancels running jobs and stops the server.\n" +
+			"Example: " + envToken + "=secret reactor-crw serve --addr :8080 -d /data/reactor",
+		Run: runServe,
+	}
+)
+
+func init() {
+	hd, _ := os.UserHomeDir()
+
+	serveCmd.Flags().StringVar(&serveAddr, "addr", "127.0.0.1:8080", "Address the server listens on")
+	serveCmd.Flags().StringVar(&serveToken, "token", "", "Token required in the Authorization: Bearer header of all requests.\nPrefer "+envToken+" since flags are kept in shell history")
+	serveCmd.Flags().IntVar(&serveMaxJobs, "max-jobs", server.DefaultMaxJobs, "Maximum amount of jobs running at once. 0 means no limit")
+	serveCmd.Flags().IntVar(&serveMaxWorkers, "max-workers", server.DefaultMaxWorkers, "Maximum amount of workers of a job. 0 means no limit")
+	serveCmd.Flags().StringVarP(&savePath, "destination", "d", hd, "Save path for content. Default value is a user's home folder")
+	serveCmd.Flags().StringVarP(&cookie, "cookie", "c", "", "User's cookie. Some content may be unavailable without it.\nPrefer --secret-file, "+envCookie+" or the credential store since flags are kept in shell history")
+	addCookieJarFlag(serveCmd)
+	addCookiesFileFlag(serveCmd)
+	addCredentialFlags(serveCmd)
+	addProxyFlags(serveCmd)
+	addTimeoutFlags(serveCmd)
+	addProxyPoolFlags(serveCmd)
+	addHeaderFlags(serveCmd)
+	addBandwidthFlags(serveCmd)
+	serveCmd.Flags().Float64VarP(&rateLimit, "rate-limit", "r", 0, "Maximum amount of requests per second shared by all jobs. 0 means no limit")
+
+	crawlerCmd.AddCommand(serveCmd)
+}
+
+func runServe(cmd *cobra.Command, _ []string) {
+	token := serveToken
+	if token == "" {
+		token = os.Getenv(envToken)
+	}
+	if token == "" && !loopback(serveAddr) {
+		log.Fatalf("%s is a public address, set %s or --token to serve on it", serveAddr, envToken)
+	}
+
+	metricsHandler := enableMetrics()
+
+	if err := configBandwidth(); err != nil {
+		log.Fatal(err)
+	}
+
+	c, err := resolveCookie()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	proxies, err := proxyConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	headers, err := headerConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	t, err := configTransport(config.Transport{
+		Headers:               reactor_crw.Headers{"Cookie": c},
+		RateLimit:             rateLimit,
+		CookieJar:             cookieJarPath(cmd),
+		CookiesFile:           cookiesFile,
+		Proxy:                 proxy,
+		ProxyRules:            proxies,
+		ProxyPool:             proxyPool,
+		ProxyStrategy:         proxyStrategy,
+		Profile:               profile,
+		RotateUserAgent:       rotateUserAgent,
+		HeaderRules:           headers,
+		ConnectTimeout:        connectTimeout,
+		TLSHandshakeTimeout:   tlsHandshakeTimeout,
+		ResponseHeaderTimeout: responseHeaderTimeout,
+		StallTimeout:          stallTimeout,
+		MaxConnsPerHost:       maxConnsPerHost,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	m := server.NewManager(func(r server.Request, l event.Listener) (*reactor_crw.Client, error) {
+		return newClient(t, handler.NewIndex(), job{
+			path:           r.Path,
+			search:         strings.Join(r.Search, ","),
+			destination:    savePath,
+			singlePage:     r.SinglePage,
+			followPosts:    r.FollowPosts,
+			comments:       r.Comments,
+			workers:        r.Workers,
+			commentsFolder: defaultCommentsFolder,
+			events:         l,
+		})
+	})
+
+	m.MaxJobs = serveMaxJobs
+	m.MaxWorkers = serveMaxWorkers
+
+	mux := http.NewServeMux()
+	mux.Handle("/", server.NewHandler(m))
+	mux.Handle(metricsPath, metricsHandler)
+
+	srv := &http.Server{
+		Addr:    serveAddr,
+		Handler: server.RequireToken(mux, token),
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		<-ctx.Done()
+		logger.Info("shutting down, cancelling running jobs")
+		m.CancelAll()
+		_ = srv.Shutdown(context.Background())
+	}()
+
+	logger.Info("listening", "addr", serveAddr)
+
+	err = srv.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+
+	_ = m.Wait(context.Background())
+	logger.Info("server stopped")
+}
+
+// loopback reports whether the address listens only on the loopback
+// interface.
+func loopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+
+	return ip != nil && ip.IsLoopback()
+}
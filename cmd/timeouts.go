@@ -0,0 +1,27 @@
+package main
+
+import (
+	"time"
+
+	"reactor-crw/config"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	connectTimeout        time.Duration
+	tlsHandshakeTimeout   time.Duration
+	responseHeaderTimeout time.Duration
+	stallTimeout          time.Duration
+	maxConnsPerHost       int
+)
+
+// addTimeoutFlags adds flags limiting requests and connections to the
+// command.
+func addTimeoutFlags(cmd *cobra.Command) {
+	cmd.Flags().DurationVar(&connectTimeout, "connect-timeout", config.DefaultConnectTimeout, "Maximum time to establish a connection")
+	cmd.Flags().DurationVar(&tlsHandshakeTimeout, "tls-timeout", config.DefaultTLSHandshakeTimeout, "Maximum time of the TLS handshake")
+	cmd.Flags().DurationVar(&responseHeaderTimeout, "response-timeout", config.DefaultResponseHeaderTimeout, "Maximum time to wait for response headers after a request is sent")
+	cmd.Flags().DurationVar(&stallTimeout, "stall-timeout", config.DefaultStallTimeout, "Abort a download if no data arrives within the time")
+	cmd.Flags().IntVar(&maxConnsPerHost, "max-conns-per-host", 0, "Maximum amount of connections to a host. 0 means no limit")
+}
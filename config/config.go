@@ -0,0 +1,482 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrNoJobs returned when the configuration doesn't declare any job.
+	ErrNoJobs = errors.New("no jobs declared")
+
+	// ErrInvalidJob returned when a job declaration is incomplete or invalid.
+	ErrInvalidJob = errors.New("invalid job")
+)
+
+// Config describes a set of crawl jobs along with global settings shared by
+// all of them.
+type Config struct {
+	// Destination contains a base directory for all jobs. Jobs may override it.
+	Destination string `yaml:"destination"`
+
+	// Workers contains the default amount of workers for each job.
+	Workers int `yaml:"workers"`
+
+	// StateDir contains a directory where the state of scheduled jobs is kept
+	// between runs. If it's empty the state isn't kept.
+	StateDir string `yaml:"state_dir"`
+
+	// Transport contains network settings shared by all jobs.
+	Transport Transport `yaml:"transport"`
+
+	// Jobs contains a list of crawl jobs that will be run one by one.
+	Jobs []Job `yaml:"jobs"`
+}
+
+// Transport describes network settings of the crawler.
+type Transport struct {
+	// Headers contains custom request headers. Example: {"Cookie": "..."}.
+	Headers map[string]string `yaml:"headers"`
+
+	// Profile contains the name of the header profile imitating a browser.
+	// Possible values: chrome, firefox, mobile. Default value is firefox.
+	Profile string `yaml:"profile"`
+
+	// RotateUserAgent enables sending a random User-Agent of the profile
+	// with each request.
+	RotateUserAgent bool `yaml:"rotate_user_agent"`
+
+	// HeaderRules override headers of requests to matching hosts. All
+	// matching rules are applied in order.
+	HeaderRules []HeaderRule `yaml:"header_rules"`
+
+	// RateLimit contains the maximum amount of requests per second. Zero
+	// means no limit.
+	RateLimit float64 `yaml:"rate_limit"`
+
+	// CookieJar contains a path to the cookie jar file created by the login
+	// command. Cookies set by the site are saved to it as well. If it's empty
+	// cookies aren't kept.
+	CookieJar string `yaml:"cookie_jar"`
+
+	// CookiesFile contains a path to cookies exported from a browser in the
+	// Netscape cookies.txt or JSON format. They're added to the cookie jar.
+	CookiesFile string `yaml:"cookies_file"`
+
+	// Proxy contains the URL of the proxy used for all requests. The http,
+	// https and socks5 schemes are supported. Credentials are set as user
+	// info. If it's empty, proxies set by the environment are used.
+	Proxy string `yaml:"proxy"`
+
+	// ProxyRules route requests to matching hosts through separate proxies.
+	// The first matching rule is used.
+	ProxyRules []ProxyRule `yaml:"proxy_rules"`
+
+	// ProxyPool contains URLs of proxies requests are distributed among.
+	// Proxies failing repeatedly are skipped for a while. It cannot be used
+	// together with Proxy.
+	ProxyPool []string `yaml:"proxy_pool"`
+
+	// ProxyStrategy defines how a proxy of the pool is chosen. Possible
+	// values: round-robin, random, least-errors. Default value is
+	// round-robin.
+	ProxyStrategy string `yaml:"proxy_strategy"`
+
+	// ConnectTimeout, TLSHandshakeTimeout and ResponseHeaderTimeout limit
+	// stages of requests. Example: 10s. Default values are 30s, 10s and 30s.
+	ConnectTimeout        time.Duration `yaml:"connect_timeout"`
+	TLSHandshakeTimeout   time.Duration `yaml:"tls_handshake_timeout"`
+	ResponseHeaderTimeout time.Duration `yaml:"response_header_timeout"`
+
+	// StallTimeout aborts a download if no data arrives within it. Default
+	// value is one minute.
+	StallTimeout time.Duration `yaml:"stall_timeout"`
+
+	// MaxConnsPerHost limits the amount of connections to a host. Zero means
+	// no limit.
+	MaxConnsPerHost int `yaml:"max_conns_per_host"`
+
+	// CacheDir contains a path to the directory crawled pages are cached in.
+	// If it's empty pages aren't cached.
+	CacheDir string `yaml:"cache_dir"`
+
+	// CacheTTL is the time cached pages are used for. Zero means they never
+	// expire.
+	CacheTTL time.Duration `yaml:"cache_ttl"`
+
+	// CacheMedia enables caching media content along with pages.
+	CacheMedia bool `yaml:"cache_media"`
+
+	// Offline disables requests, so all pages are read from the cache.
+	Offline bool `yaml:"offline"`
+
+	// RecordDir contains a path to the fixture archive all responses are
+	// recorded to. If it's empty responses aren't recorded.
+	RecordDir string `yaml:"record_dir"`
+
+	// ReplayDir contains a path to the fixture archive responses are served
+	// from instead of making requests. It cannot be used together with
+	// RecordDir.
+	ReplayDir string `yaml:"replay_dir"`
+}
+
+// HeaderRule describes headers overridden for a set of hosts.
+type HeaderRule struct {
+	// Hosts lists host patterns. Example: [joyreactor.cc, "*.joyreactor.cc"].
+	Hosts []string `yaml:"hosts"`
+
+	// Headers contains overridden headers. Example: {"Referer": "..."}.
+	Headers map[string]string `yaml:"headers"`
+}
+
+// ProxyRule describes a proxy used for a set of hosts.
+type ProxyRule struct {
+	// Hosts lists host patterns. Example: [joyreactor.cc, "*.joyreactor.cc"].
+	Hosts []string `yaml:"hosts"`
+
+	// Proxy contains the proxy URL or "direct" to disable proxying.
+	Proxy string `yaml:"proxy"`
+}
+
+// Job describes a single crawl target.
+type Job struct {
+	// Name identifies the job. If it's empty the job path is used.
+	Name string `yaml:"name"`
+
+	// Path contains a full page URL.
+	Path string `yaml:"path"`
+
+	// Search contains a list of content types that should be downloaded.
+	Search []string `yaml:"search"`
+
+	// Pages limits the range of crawled pages.
+	Pages Pages `yaml:"pages"`
+
+	SinglePage  bool `yaml:"single_page"`
+	FollowPosts bool `yaml:"follow_posts"`
+	Comments    bool `yaml:"comments"`
+
+	// Workers overrides Config.Workers for this job.
+	Workers int `yaml:"workers"`
+
+	// Destination overrides Config.Destination for this job.
+	Destination string `yaml:"destination"`
+
+	// Folder is a template of the folder name the content is saved to. It's
+	// executed against FolderData.
+	Folder string `yaml:"folder"`
+
+	// CommentsFolder is the subfolder for content from comments. Default
+	// value is comments. "." saves such content along with the rest.
+	CommentsFolder string `yaml:"comments_folder"`
+
+	// FileName is a template of saved file names. It's executed against
+	// fs.NameData.
+	FileName string `yaml:"filename"`
+
+	// Schedule is a standard cron expression used by the daemon to run the
+	// job periodically. Example: "*/30 * * * *" or "@hourly".
+	Schedule string `yaml:"schedule"`
+
+	// Filter limits the posts and content saved by the job.
+	Filter Filter `yaml:"filter"`
+}
+
+// Filter describes rules of posts and content that should be saved. Zero
+// values disable the respective rule.
+type Filter struct {
+	// MinSize and MaxSize limit the size of content. Example: 300K, 20MB.
+	MinSize string `yaml:"min_size"`
+	MaxSize string `yaml:"max_size"`
+
+	// Extensions lists allowed extensions of content links. Example: [jpg, png].
+	Extensions []string `yaml:"ext"`
+
+	// MIMETypes lists allowed MIME types of content. Example: ["image/*"].
+	MIMETypes []string `yaml:"mime"`
+
+	// ExcludeComments skips content found in post comments.
+	ExcludeComments bool `yaml:"exclude_comments"`
+
+	// MinWidth and MinHeight limit dimensions of images in pixels.
+	MinWidth  int `yaml:"min_width"`
+	MinHeight int `yaml:"min_height"`
+
+	// Aspect contains the required aspect ratio of images. Example: 16:9, 1.5.
+	Aspect string `yaml:"aspect"`
+
+	// ExcludeTags skips posts with any of the tags.
+	ExcludeTags []string `yaml:"exclude_tags"`
+
+	// RequireTags skips posts without all of the tags.
+	RequireTags []string `yaml:"require_tags"`
+
+	// MinRating skips posts with a lower rating.
+	MinRating float64 `yaml:"min_rating"`
+
+	// Since and Until skip posts published before and after the dates
+	// respectively. Example: 2024-03-01.
+	Since string `yaml:"since"`
+	Until string `yaml:"until"`
+}
+
+// Pages describes a range of pages. Zero values mean the first and the last
+// available page respectively.
+type Pages struct {
+	From int `yaml:"from"`
+	To   int `yaml:"to"`
+}
+
+// unsafeFolderChars matches characters that cannot be used in folder names on
+// common file systems. Path separators are among them, so a folder is always
+// created within the destination.
+var unsafeFolderChars = regexp.MustCompile(`[/\\<>:"|?*\x00-\x1f]`)
+
+// FolderData contains values available within Job.Folder template.
+type FolderData struct {
+	// Name is a job name.
+	Name string
+
+	// Path is a job path converted to a folder name. Example: _tag_someTag.
+	Path string
+}
+
+const (
+	defaultWorkers = 1
+	defaultFolder  = "{{.Path}}"
+
+	defaultCommentsFolder = "comments"
+)
+
+var defaultSearch = []string{"image", "gif"}
+
+// Default timeouts of the transport. They're used when the configuration
+// doesn't set them.
+const (
+	DefaultConnectTimeout        = 30 * time.Second
+	DefaultTLSHandshakeTimeout   = 10 * time.Second
+	DefaultResponseHeaderTimeout = 30 * time.Second
+	DefaultStallTimeout          = time.Minute
+)
+
+// Load reads the configuration file by its path. All environment variables
+// like $VAR or ${VAR} within values of the file are expanded, so secrets such
+// as cookies can be kept out of the file. $$ is replaced with a literal $.
+func Load(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read config %s: %w", path, err)
+	}
+
+	return Parse(data)
+}
+
+// Parse parses the YAML configuration, expands environment variables, sets
+// default values and validates the result. Variables are expanded within
+// values only, so their content cannot change the structure of the file.
+func Parse(data []byte) (*Config, error) {
+	var root yaml.Node
+	err := yaml.Unmarshal(data, &root)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse config: %w", err)
+	}
+
+	expandEnv(&root)
+
+	// Expanded values are encoded back, so they're quoted when needed and the
+	// decoder still rejects unknown fields.
+	data, err = yaml.Marshal(&root)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse config: %w", err)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+
+	c := &Config{}
+	err = dec.Decode(c)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("cannot parse config: %w", err)
+	}
+
+	if c.Workers < 1 {
+		c.Workers = defaultWorkers
+	}
+
+	c.Transport.init()
+
+	if len(c.Jobs) == 0 {
+		return nil, ErrNoJobs
+	}
+
+	for i := range c.Jobs {
+		err = c.Jobs[i].init(c)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// expandEnv expands environment variables within scalar values of the node
+// and its children. $$ is replaced with a literal $. The type of an unquoted
+// value is resolved again after expansion, so variables may contain numbers
+// and booleans.
+func expandEnv(n *yaml.Node) {
+	if n.Kind == yaml.ScalarNode && strings.Contains(n.Value, "$") {
+		n.Value = os.Expand(n.Value, func(name string) string {
+			if name == "$" {
+				return "$"
+			}
+			return os.Getenv(name)
+		})
+		if n.Style == 0 {
+			n.Tag = ""
+		}
+	}
+
+	for _, c := range n.Content {
+		expandEnv(c)
+	}
+}
+
+// init sets default values of the transport.
+func (t *Transport) init() {
+	if t.ConnectTimeout <= 0 {
+		t.ConnectTimeout = DefaultConnectTimeout
+	}
+	if t.TLSHandshakeTimeout <= 0 {
+		t.TLSHandshakeTimeout = DefaultTLSHandshakeTimeout
+	}
+	if t.ResponseHeaderTimeout <= 0 {
+		t.ResponseHeaderTimeout = DefaultResponseHeaderTimeout
+	}
+	if t.StallTimeout <= 0 {
+		t.StallTimeout = DefaultStallTimeout
+	}
+}
+
+// init sets default values of the job and validates it.
+func (j *Job) init(c *Config) error {
+	if j.Path == "" {
+		return fmt.Errorf("%w: path is required", ErrInvalidJob)
+	}
+
+	if _, err := url.ParseRequestURI(j.Path); err != nil {
+		return fmt.Errorf("%w: invalid path %s", ErrInvalidJob, j.Path)
+	}
+
+	if j.Name == "" {
+		j.Name = j.Path
+	}
+
+	if len(j.Search) == 0 {
+		j.Search = defaultSearch
+	}
+
+	if j.Workers < 1 {
+		j.Workers = c.Workers
+	}
+
+	if j.Destination == "" {
+		j.Destination = c.Destination
+	}
+
+	if j.Pages.From < 0 || j.Pages.To < 0 || (j.Pages.To > 0 && j.Pages.From > j.Pages.To) {
+		return fmt.Errorf("%w: invalid pages range of %s", ErrInvalidJob, j.Name)
+	}
+
+	if j.Folder == "" {
+		j.Folder = defaultFolder
+	}
+
+	switch j.CommentsFolder {
+	case "":
+		j.CommentsFolder = defaultCommentsFolder
+	case ".":
+		j.CommentsFolder = ""
+	}
+
+	if j.Schedule != "" {
+		if _, err := cron.ParseStandard(j.Schedule); err != nil {
+			return fmt.Errorf("%w: invalid schedule of %s: %s", ErrInvalidJob, j.Name, err)
+		}
+	}
+
+	if _, err := j.FolderTemplate(); err != nil {
+		return err
+	}
+
+	if _, err := j.FileNameTemplate(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// FolderName executes the folder template of the job.
+func (j *Job) FolderName() (string, error) {
+	t, err := j.FolderTemplate()
+	if err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(j.Path)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid path %s", ErrInvalidJob, j.Path)
+	}
+
+	b := strings.Builder{}
+	err = t.Execute(&b, FolderData{
+		Name: unsafeFolderChars.ReplaceAllString(j.Name, "_"),
+		Path: strings.Replace(u.Path, "/", "_", -1),
+	})
+	if err != nil {
+		return "", fmt.Errorf("cannot resolve folder of %s: %w", j.Name, err)
+	}
+
+	folder := unsafeFolderChars.ReplaceAllString(strings.TrimSpace(b.String()), "_")
+	if folder == "" || folder == "." || folder == ".." {
+		return "", fmt.Errorf("%w: invalid folder %q of %s", ErrInvalidJob, folder, j.Name)
+	}
+
+	return folder, nil
+}
+
+// FolderTemplate parses the folder template of the job.
+func (j *Job) FolderTemplate() (*template.Template, error) {
+	t, err := template.New("folder").Parse(j.Folder)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid folder of %s: %s", ErrInvalidJob, j.Name, err)
+	}
+
+	return t, nil
+}
+
+// FileNameTemplate parses the file name template of the job. It returns nil
+// if the template isn't set.
+func (j *Job) FileNameTemplate() (*template.Template, error) {
+	if j.FileName == "" {
+		return nil, nil
+	}
+
+	t, err := template.New("filename").Parse(j.FileName)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid filename of %s: %s", ErrInvalidJob, j.Name, err)
+	}
+
+	return t, nil
+}
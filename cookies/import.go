@@ -0,0 +1,150 @@
+package cookies
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// httpOnlyPrefix marks HttpOnly cookies in Netscape cookie files.
+const httpOnlyPrefix = "#HttpOnly_"
+
+// ReadFile reads cookies exported from a browser. Both the Netscape
+// cookies.txt format and JSON exports of browser extensions are supported.
+// The format is detected by the content.
+func ReadFile(path string) ([]Entry, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read cookies: %w", err)
+	}
+
+	var entries []Entry
+	if t := bytes.TrimSpace(data); len(t) > 0 && (t[0] == '[' || t[0] == '{') {
+		entries, err = ParseJSON(t)
+	} else {
+		entries, err = ParseNetscape(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse cookies %s: %w", path, err)
+	}
+
+	return entries, nil
+}
+
+// ParseNetscape parses cookies in the Netscape cookies.txt format used by
+// curl, wget and browser extensions. Each line contains tab separated
+// domain, subdomains flag, path, secure flag, expiration time, name and
+// value. Zero expiration time means a session cookie.
+func ParseNetscape(data []byte) ([]Entry, error) {
+	var entries []Entry
+
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimRight(s.Text(), "\r")
+
+		httpOnly := strings.HasPrefix(line, httpOnlyPrefix)
+		if httpOnly {
+			line = strings.TrimPrefix(line, httpOnlyPrefix)
+		}
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		f := strings.Split(line, "\t")
+		if len(f) < 6 {
+			return nil, fmt.Errorf("line %d: expected 7 fields, got %d", n, len(f))
+		}
+		if len(f) == 6 {
+			// Cookies with empty values may lose the trailing tab.
+			f = append(f, "")
+		}
+
+		sec, err := strconv.ParseInt(f[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid expiration time %q", n, f[4])
+		}
+
+		e := Entry{
+			Name:     f[5],
+			Value:    f[6],
+			Domain:   f[0],
+			Path:     f[2],
+			Secure:   strings.EqualFold(f[3], "TRUE"),
+			HttpOnly: httpOnly,
+			HostOnly: !strings.EqualFold(f[1], "TRUE"),
+		}
+		if sec > 0 {
+			e.Expires = time.Unix(sec, 0).UTC()
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, s.Err()
+}
+
+// jsonCookie is a cookie exported by browser extensions like EditThisCookie
+// and Cookie-Editor or by browser automation tools.
+type jsonCookie struct {
+	Name           string   `json:"name"`
+	Value          string   `json:"value"`
+	Domain         string   `json:"domain"`
+	Path           string   `json:"path"`
+	Secure         bool     `json:"secure"`
+	HttpOnly       bool     `json:"httpOnly"`
+	HostOnly       bool     `json:"hostOnly"`
+	Session        bool     `json:"session"`
+	ExpirationDate *float64 `json:"expirationDate"`
+	Expires        *float64 `json:"expires"`
+}
+
+// ParseJSON parses cookies exported as a JSON array of objects. A single
+// object is accepted as well. Expiration times are unix timestamps in
+// seconds.
+func ParseJSON(data []byte) ([]Entry, error) {
+	var cookies []jsonCookie
+
+	if t := bytes.TrimSpace(data); len(t) > 0 && t[0] == '{' {
+		var c jsonCookie
+		if err := json.Unmarshal(t, &c); err != nil {
+			return nil, err
+		}
+		cookies = append(cookies, c)
+	} else if err := json.Unmarshal(data, &cookies); err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(cookies))
+	for i, c := range cookies {
+		if c.Name == "" || c.Domain == "" {
+			return nil, fmt.Errorf("cookie %d: name and domain are required", i)
+		}
+
+		e := Entry{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+			HostOnly: c.HostOnly,
+		}
+
+		exp := c.ExpirationDate
+		if exp == nil {
+			exp = c.Expires
+		}
+		if !c.Session && exp != nil && *exp > 0 {
+			e.Expires = time.Unix(int64(*exp), 0).UTC()
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,91 @@
+//go:build unit
+// +build unit
+
+package cookies_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"reactor-crw/cookies"
+)
+
+func TestParseNetscape(t *testing.T) {
+	t.Log("Given the need to import cookies.txt.")
+	{
+		t.Log("When the file is valid.")
+		{
+			entries, err := cookies.ParseNetscape([]byte("# Netscape HTTP Cookie File\n\n" +
+				".joyreactor.cc\tTRUE\t/\tFALSE\t2000000000\tremember\tme\n" +
+				"#HttpOnly_joyreactor.cc\tFALSE\t/\tTRUE\t0\tsession\tsecret\r\n" +
+				"joyreactor.cc\tFALSE\t/\tFALSE\t0\tempty\n"))
+			require.NoError(t, err, "Wasn't expected an error during parsing")
+			require.Equal(t, []cookies.Entry{
+				{Name: "remember", Value: "me", Domain: ".joyreactor.cc", Path: "/", Expires: time.Unix(2000000000, 0).UTC()},
+				{Name: "session", Value: "secret", Domain: "joyreactor.cc", Path: "/", Secure: true, HttpOnly: true, HostOnly: true},
+				{Name: "empty", Domain: "joyreactor.cc", Path: "/", HostOnly: true},
+			}, entries)
+		}
+
+		t.Log("When a line is invalid.")
+		{
+			_, err := cookies.ParseNetscape([]byte("joyreactor.cc\tFALSE\t/\n"))
+			require.Error(t, err, "Expected an error for an incomplete line")
+		}
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	t.Log("Given the need to import cookies exported as JSON.")
+	{
+		t.Log("When the export is valid.")
+		{
+			entries, err := cookies.ParseJSON([]byte(`[
+				{"name": "remember", "value": "me", "domain": ".joyreactor.cc", "path": "/", "expirationDate": 2000000000.5},
+				{"name": "session", "value": "secret", "domain": "joyreactor.cc", "path": "/", "hostOnly": true, "session": true, "httpOnly": true}
+			]`))
+			require.NoError(t, err, "Wasn't expected an error during parsing")
+			require.Equal(t, []cookies.Entry{
+				{Name: "remember", Value: "me", Domain: ".joyreactor.cc", Path: "/", Expires: time.Unix(2000000000, 0).UTC()},
+				{Name: "session", Value: "secret", Domain: "joyreactor.cc", Path: "/", HttpOnly: true, HostOnly: true},
+			}, entries)
+		}
+
+		t.Log("When a cookie has no domain.")
+		{
+			_, err := cookies.ParseJSON([]byte(`{"name": "session", "value": "secret"}`))
+			require.Error(t, err, "Expected an error for a cookie without domain")
+		}
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	dir := t.TempDir()
+	u, _ := url.Parse("http://joyreactor.cc/tag/art")
+
+	files := map[string]string{
+		"cookies.txt":  "joyreactor.cc\tFALSE\t/\tFALSE\t0\tsession\tsecret\n",
+		"cookies.json": `[{"name": "session", "value": "secret", "domain": "joyreactor.cc", "path": "/", "hostOnly": true}]`,
+	}
+
+	t.Log("Given the need to detect the format of exported cookies.")
+	{
+		for name, content := range files {
+			path := filepath.Join(dir, name)
+			require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+
+			entries, err := cookies.ReadFile(path)
+			require.NoError(t, err, "Wasn't expected an error for %s", name)
+
+			jar := cookies.New("")
+			require.NoError(t, jar.Add(entries))
+			require.Equal(t, []*http.Cookie{{Name: "session", Value: "secret"}}, jar.Cookies(u), name)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package cookies
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+
+	"reactor-crw/atomicfile"
+	"reactor-crw/logging"
+)
+
+// Entry is a cookie stored in the jar file.
+type Entry struct {
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Domain   string    `json:"domain"`
+	Path     string    `json:"path"`
+	Expires  time.Time `json:"expires"`
+	Secure   bool      `json:"secure,omitempty"`
+	HttpOnly bool      `json:"http_only,omitempty"`
+
+	// HostOnly means the cookie is sent only to the host that set it and not
+	// to its subdomains.
+	HostOnly bool `json:"host_only,omitempty"`
+}
+
+// key identifies the entry the same way browsers do.
+func (e Entry) key() string {
+	return e.Domain + ";" + e.Path + ";" + e.Name
+}
+
+// expired reports whether the entry is expired at the moment. Entries
+// without expiration time never expire.
+func (e Entry) expired(now time.Time) bool {
+	return !e.Expires.IsZero() && !e.Expires.After(now)
+}
+
+// cookie converts the entry to a cookie and the URL it should be set for.
+func (e Entry) cookie() (*url.URL, *http.Cookie) {
+	scheme := "http"
+	if e.Secure {
+		scheme = "https"
+	}
+
+	c := &http.Cookie{
+		Name:     e.Name,
+		Value:    e.Value,
+		Path:     e.Path,
+		Expires:  e.Expires,
+		Secure:   e.Secure,
+		HttpOnly: e.HttpOnly,
+	}
+	if !e.HostOnly {
+		c.Domain = e.Domain
+	}
+
+	return &url.URL{Scheme: scheme, Host: e.Domain, Path: e.Path}, c
+}
+
+// Jar is an http.CookieJar that keeps its cookies in a file, so sessions
+// survive between runs. The file is loaded by Open and rewritten every time
+// stored cookies change. Session cookies are kept as well. It's safe for
+// concurrent use.
+type Jar struct {
+	// Logger receives a record if the jar cannot be saved. It's optional.
+	Logger logging.Logger
+
+	mu      sync.Mutex
+	path    string
+	jar     *cookiejar.Jar
+	entries map[string]Entry
+}
+
+// New creates an empty jar saved to the file by the path. If the path is
+// empty, cookies are kept in memory only.
+func New(path string) *Jar {
+	j, _ := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+
+	return &Jar{
+		path:    path,
+		jar:     j,
+		entries: make(map[string]Entry),
+	}
+}
+
+// Open creates a jar with cookies loaded from the file by the path. If the
+// file doesn't exist, the jar is empty and the file is created once a cookie
+// is set.
+func Open(path string) (*Jar, error) {
+	j := New(path)
+
+	data, err := ioutil.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read cookie jar: %w", err)
+	}
+
+	var entries []Entry
+	if err = json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("cannot parse cookie jar %s: %w", path, err)
+	}
+
+	j.add(entries)
+
+	return j, nil
+}
+
+// Add stores the entries in the jar and saves it. Expired entries are
+// skipped.
+func (j *Jar) Add(entries []Entry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.add(entries)
+
+	return j.save()
+}
+
+func (j *Jar) add(entries []Entry) {
+	now := time.Now()
+	for _, e := range entries {
+		if e.expired(now) {
+			continue
+		}
+		e.Domain = strings.TrimPrefix(strings.ToLower(e.Domain), ".")
+		if e.Path == "" {
+			e.Path = "/"
+		}
+
+		j.entries[e.key()] = e
+		u, c := e.cookie()
+		j.jar.SetCookies(u, []*http.Cookie{c})
+	}
+}
+
+// Entries returns all stored cookies that aren't expired sorted by domain,
+// path and name.
+func (j *Jar) Entries() []Entry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.list()
+}
+
+func (j *Jar) list() []Entry {
+	now := time.Now()
+
+	res := make([]Entry, 0, len(j.entries))
+	for _, e := range j.entries {
+		if !e.expired(now) {
+			res = append(res, e)
+		}
+	}
+
+	sort.Slice(res, func(a, b int) bool {
+		return res[a].key() < res[b].key()
+	})
+
+	return res
+}
+
+// SetCookies implements http.CookieJar. The jar is saved if stored cookies
+// are changed. Cookies for a domain the URL doesn't belong to are rejected.
+func (j *Jar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.jar.SetCookies(u, cookies)
+
+	changed := false
+	now := time.Now()
+	for _, c := range cookies {
+		e := newEntry(u, c, now)
+		if !e.allowed(u.Hostname()) {
+			logging.OrDiscard(j.Logger).Debug("cookie rejected", "url", u.String(), "name", e.Name, "domain", e.Domain)
+			continue
+		}
+		old, ok := j.entries[e.key()]
+
+		switch {
+		case e.expired(now):
+			if ok {
+				delete(j.entries, e.key())
+				changed = true
+			}
+		case !ok || old != e:
+			j.entries[e.key()] = e
+			changed = true
+		}
+	}
+
+	if !changed {
+		return
+	}
+
+	if err := j.save(); err != nil {
+		logging.OrDiscard(j.Logger).Warn("cannot save cookie jar", "path", j.path, "error", err)
+	}
+}
+
+// Cookies implements http.CookieJar.
+func (j *Jar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+// newEntry converts the cookie received from the URL to an entry. Cookies
+// removed by the server are converted to expired entries.
+func newEntry(u *url.URL, c *http.Cookie, now time.Time) Entry {
+	e := Entry{
+		Name:     c.Name,
+		Value:    c.Value,
+		Domain:   strings.TrimPrefix(strings.ToLower(c.Domain), "."),
+		Path:     c.Path,
+		Secure:   c.Secure,
+		HttpOnly: c.HttpOnly,
+	}
+
+	if e.Domain == "" {
+		e.Domain = strings.ToLower(u.Hostname())
+		e.HostOnly = true
+	}
+	if e.Path == "" || e.Path[0] != '/' {
+		e.Path = defaultPath(u.Path)
+	}
+
+	switch {
+	case c.MaxAge < 0:
+		e.Expires = now.Add(-time.Second)
+	case c.MaxAge > 0:
+		e.Expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+	case !c.Expires.IsZero():
+		e.Expires = c.Expires.UTC()
+	}
+
+	return e
+}
+
+// allowed reports whether the host may set the entry as described in RFC 6265
+// section 5.3: the domain of the entry must be the host or one of its parent
+// domains, and it mustn't be a public suffix.
+func (e Entry) allowed(host string) bool {
+	host = strings.ToLower(host)
+	if e.HostOnly || e.Domain == host {
+		return true
+	}
+	if net.ParseIP(host) != nil || !strings.HasSuffix(host, "."+e.Domain) {
+		return false
+	}
+
+	suffix, _ := publicsuffix.PublicSuffix(e.Domain)
+
+	return suffix != e.Domain
+}
+
+// defaultPath returns the directory of the URL path as described in RFC 6265
+// section 5.1.4.
+func defaultPath(p string) string {
+	if p == "" || p[0] != '/' {
+		return "/"
+	}
+
+	i := strings.LastIndex(p, "/")
+	if i == 0 {
+		return "/"
+	}
+
+	return p[:i]
+}
+
+// save writes stored cookies to the file. The file is replaced atomically and
+// it's readable only by its owner since it contains session cookies.
+func (j *Jar) save() error {
+	if j.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(j.list(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return atomicfile.Write(j.path, bytes.NewReader(data), 0600)
+}
@@ -0,0 +1,76 @@
+//go:build unit
+// +build unit
+
+package cookies_test
+
+import (
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"reactor-crw/cookies"
+)
+
+func TestJar_SetCookies(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jar", "cookies.json")
+	u, _ := url.Parse("http://joyreactor.cc/login")
+
+	t.Log("Given the need to keep cookies between runs.")
+	{
+		t.Log("When the jar file doesn't exist.")
+		{
+			jar, err := cookies.Open(path)
+			require.NoError(t, err, "Wasn't expected an error for a missing jar")
+			require.Empty(t, jar.Entries())
+
+			jar.SetCookies(u, []*http.Cookie{
+				{Name: "session", Value: "secret"},
+				{Name: "remember", Value: "me", Domain: ".joyreactor.cc", MaxAge: 3600},
+			})
+		}
+
+		t.Log("When the jar is opened again.")
+		{
+			jar, err := cookies.Open(path)
+			require.NoError(t, err, "Wasn't expected an error during loading")
+			require.Len(t, jar.Entries(), 2)
+
+			sub, _ := url.Parse("http://img10.joyreactor.cc/pics")
+			require.Len(t, jar.Cookies(u), 2, "Expected all cookies for the host")
+			require.Equal(t, []*http.Cookie{{Name: "remember", Value: "me"}}, jar.Cookies(sub), "Expected only domain cookies for the subdomain")
+		}
+
+		t.Log("When the site removes a cookie.")
+		{
+			jar, _ := cookies.Open(path)
+			jar.SetCookies(u, []*http.Cookie{{Name: "session", MaxAge: -1}})
+
+			jar, err := cookies.Open(path)
+			require.NoError(t, err, "Wasn't expected an error during loading")
+			require.Len(t, jar.Entries(), 1)
+			require.Equal(t, "remember", jar.Entries()[0].Name)
+		}
+
+		t.Log("When another site sets a cookie for the domain.")
+		{
+			path := filepath.Join(t.TempDir(), "cookies.json")
+			other, _ := url.Parse("http://example.org/")
+
+			jar, _ := cookies.Open(path)
+			jar.SetCookies(other, []*http.Cookie{
+				{Name: "session", Value: "planted", Domain: "joyreactor.cc"},
+				{Name: "tld", Value: "planted", Domain: ".org"},
+				{Name: "own", Value: "kept", Domain: "example.org"},
+			})
+
+			jar, err := cookies.Open(path)
+			require.NoError(t, err, "Wasn't expected an error during loading")
+			require.Len(t, jar.Entries(), 1, "Expected only the cookie of the site to be saved")
+			require.Equal(t, "own", jar.Entries()[0].Name)
+			require.Empty(t, jar.Cookies(u), "Expected no cookies sent to another host")
+		}
+	}
+}
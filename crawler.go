@@ -1,10 +1,22 @@
 package reactor_crw
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net/url"
+	"path"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"reactor-crw/event"
+	"reactor-crw/handler"
+	"reactor-crw/logging"
+	"reactor-crw/metrics"
 	"reactor-crw/parser"
 )
 
@@ -14,6 +26,16 @@ type Transport interface {
 	FetchData(url string) (io.ReadCloser, error)
 }
 
+// ErrProbeUnsupported returned by transports wrapping a Transport that isn't
+// a Prober.
+var ErrProbeUnsupported = errors.New("probing isn't supported by transport")
+
+// Prober is implemented by transports that can get information about content
+// without fetching it.
+type Prober interface {
+	Probe(url string) (ContentInfo, error)
+}
+
 // HtmlCrawler allows crawling HTML pages using provided transport.Transport and
 // parser.Parser. The crawler doesn't do anything with the content itself it only
 // gathers the collection of sources links.
@@ -28,46 +50,363 @@ type HtmlCrawler struct {
 	// value. If its value set to true the crawler will try to calculate the
 	// number of available pages using the source page.
 	MultiPage bool
+
+	// FirstPage and LastPage limit the range of pages crawled when MultiPage
+	// is enabled. Zero values mean the first and the last available page
+	// respectively.
+	FirstPage int
+	LastPage  int
+
+	// FollowPosts makes the crawler treat every page as a listing. Instead of
+	// collecting content from the page itself it collects links to posts and
+	// fetches each post page to get its full content set. This way truncated
+	// and collapsed posts will be crawled as well.
+	FollowPosts bool
+
+	// Comments allows crawling the whole comment tree of each post including
+	// lazily loaded comments. Content found in comments is marked with
+	// handler.Source.Comment. Since comments belong to posts, enabling it makes
+	// the crawler follow posts the same way as FollowPosts does.
+	Comments bool
+
+	// MaxWorkers limits the amount of post pages fetched at the same time when
+	// FollowPosts is enabled. Values lower than 1 are treated as 1.
+	MaxWorkers int
+
+	// PostFilter skips posts by their tags, rating and publication time.
+	// Content of skipped posts and their comments isn't collected.
+	PostFilter PostFilter
+
+	// Events receives an event for each crawled page. It's optional.
+	Events event.Listener
+
+	// Metrics records fetched pages and discovered content sources. It's
+	// optional.
+	Metrics *metrics.Metrics
+
+	// Logger receives records about crawled pages. It's optional.
+	Logger logging.Logger
 }
 
 // Fetch retrieves content sources from the page using the path value. Depending
 // on HtmlCrawler.MultiPage it may fetch content links from multiple pages.
-func (c *HtmlCrawler) Fetch(path string, search []string) (parser.QueryResult, error) {
+func (c *HtmlCrawler) Fetch(path string, search []string) ([]handler.Source, error) {
+	return c.FetchContext(context.Background(), path, search)
+}
+
+// FetchContext works the same way as Fetch but stops crawling as soon as the
+// context is done. The context is checked between pages, so pages that are
+// already being fetched will be finished. In this case the context error is
+// returned.
+func (c *HtmlCrawler) FetchContext(ctx context.Context, path string, search []string) ([]handler.Source, error) {
+	var (
+		sources []handler.Source
+		err     error
+	)
+
 	if c.MultiPage {
-		return c.multiPage(path, search)
+		sources, err = c.multiPage(ctx, path, search)
+	} else {
+		sources, err = c.singlePage(ctx, path, search)
 	}
 
-	collectedData := make(parser.QueryResult)
+	c.Metrics.ItemsDiscovered(len(sources))
+
+	return sources, err
+}
+
+func (c *HtmlCrawler) singlePage(ctx context.Context, path string, search []string) ([]handler.Source, error) {
+	collectedData := newCollection()
 
-	err := c.fetch(path, search, collectedData)
+	err := c.fetch(ctx, path, search, collectedData)
 	if err != nil {
 		return nil, err
 	}
 
-	return collectedData, nil
+	return collectedData.sources(), nil
 }
 
 // fetchMultiPage will fetch content sources from multiple pages. It'll try to
-// retrieve the number of pages and consequently crawl all pages.
-func (c *HtmlCrawler) multiPage(path string, search []string) (parser.QueryResult, error) {
+// retrieve the number of pages and consequently crawl all pages within the
+// range set by HtmlCrawler.FirstPage and HtmlCrawler.LastPage.
+func (c *HtmlCrawler) multiPage(ctx context.Context, path string, search []string) ([]handler.Source, error) {
 	maxPage, err := c.resolveMaxPage(path)
 	if err != nil {
 		return nil, err
 	}
 
-	collectedData := make(parser.QueryResult)
+	if c.LastPage > 0 && c.LastPage < maxPage {
+		maxPage = c.LastPage
+	}
 
-	for p := 1; p <= maxPage; p++ {
-		err = c.fetch(fmt.Sprintf("%s/%d", path, p), search, collectedData)
-		if err != nil {
+	minPage := 1
+	if c.FirstPage > minPage {
+		minPage = c.FirstPage
+	}
+
+	c.log().Info("pages resolved", "path", path, "first", minPage, "last", maxPage)
+
+	collectedData := newCollection()
+
+	if c.PostFilter.Since.IsZero() {
+		for p := minPage; p <= maxPage; p++ {
+			if err = ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			page := fmt.Sprintf("%s/%d", path, p)
+			err = c.fetch(ctx, page, search, collectedData)
+			if err != nil && !c.skipped(page, err) {
+				return nil, err
+			}
+		}
+
+		return collectedData.sources(), nil
+	}
+
+	// Pages with greater numbers contain newer posts, so they're crawled from
+	// the last one until posts become older than PostFilter.Since.
+	for p := maxPage; p >= minPage; p-- {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		page := fmt.Sprintf("%s/%d", path, p)
+		pageData := newCollection()
+		err = c.fetch(ctx, page, search, pageData)
+		if err != nil && !c.skipped(page, err) {
 			return nil, err
 		}
+		collectedData.merge(pageData)
+
+		if !pageData.latest.IsZero() && pageData.latest.Before(c.PostFilter.Since) {
+			c.log().Info("pagination stopped, posts are older than since", "path", path, "page", p)
+			break
+		}
+	}
+
+	return collectedData.sources(), nil
+}
+
+func (c *HtmlCrawler) fetch(ctx context.Context, path string, search []string, col *collection) error {
+	if c.FollowPosts || c.Comments {
+		return c.fetchPosts(ctx, path, search, col)
+	}
+
+	_, err := c.fetchPostSources(path, path, search, col)
+
+	return err
+}
+
+// fetchPosts collects links to posts from the listing page and crawls every
+// post page using a simple worker pool limited by HtmlCrawler.MaxWorkers.
+// Posts that aren't fetched yet are skipped as soon as the context is done.
+func (c *HtmlCrawler) fetchPosts(ctx context.Context, path string, search []string, col *collection) error {
+	links, err := c.resolvePostLinks(path)
+	if err != nil || len(links) == 0 {
+		return err
+	}
+
+	tasks := make(chan string, len(links))
+	for _, l := range links {
+		tasks <- l
+	}
+	close(tasks)
+
+	workers := c.MaxWorkers
+	if workers < 1 {
+		workers = 1
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range tasks {
+				if ctx.Err() != nil {
+					return
+				}
+
+				postData := newCollection()
+				err := c.fetchPost(t, search, postData)
+				if err != nil && c.skipped(t, err) {
+					err = nil
+				}
+
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				col.merge(postData)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+
+	return firstErr
+}
+
+// fetchPost crawls a single post page and, if HtmlCrawler.Comments is enabled,
+// its comment tree.
+func (c *HtmlCrawler) fetchPost(link string, search []string, col *collection) error {
+	matched, err := c.fetchPostSources(link, link, search, col)
+	if err != nil || !matched || !c.Comments {
+		return err
+	}
+
+	commentsLink, err := resolveCommentsLink(link)
+	if err != nil {
+		return err
+	}
+
+	err = c.fetchSources(commentsLink, link, commentScope, search, col.comments)
+	if err != nil && c.skipped(commentsLink, err) {
+		return nil
+	}
+
+	return err
+}
+
+// fetchSources crawls the page by the path and adds found content to the
+// sources. Each new source is marked with its content type and the page it
+// belongs to.
+func (c *HtmlCrawler) fetchSources(path, page, scope string, search []string, sources map[string]handler.Source) error {
+	found := make(parser.QueryResult)
+
+	err := c.fetchPage(path, buildQuery(scope, search), found)
+	if err != nil {
+		return err
+	}
+
+	for u, q := range found {
+		if _, ok := sources[u]; !ok {
+			sources[u] = handler.Source{
+				URL:     u,
+				Type:    queryContentType(q),
+				Page:    page,
+				Comment: scope == commentScope,
+			}
+		}
+	}
+
+	return nil
+}
+
+// fetchPostSources crawls posts on the page by the path and adds their content
+// to the collection. If HtmlCrawler.PostFilter is set, content of posts that
+// don't match it is skipped. It reports whether any post matched the filter.
+func (c *HtmlCrawler) fetchPostSources(path, page string, search []string, col *collection) (bool, error) {
+	if c.PostFilter.Empty() {
+		return true, c.fetchSources(path, page, postScope, search, col.posts)
+	}
+
+	body, err := c.Transport.FetchData(path)
+	if err != nil {
+		return false, err
+	}
+
+	defer func(b io.ReadCloser) {
+		_ = b.Close()
+	}(body)
+
+	q := buildQuery(postScope, search)
+	q[htmlPostTime] = "data-time"
+
+	blocks, err := c.Parser.FindBlocks(body, htmlPost, q, []string{htmlPostTag, htmlPostRating})
+	if err != nil {
+		return false, fmt.Errorf("cannot apply crawler: %w", err)
+	}
+
+	matched, found := false, 0
+	for _, b := range blocks {
+		p := parsePost(b)
+		if p.published.After(col.latest) {
+			col.latest = p.published
+		}
+
+		if reason := c.PostFilter.check(p); reason != "" {
+			c.log().Debug("post skipped", "page", path, "reason", reason)
+			continue
+		}
+		matched = true
+
+		for u, q := range b.Result {
+			if q == htmlPostTime {
+				continue
+			}
+			found++
+			if _, ok := col.posts[u]; !ok {
+				col.posts[u] = handler.Source{
+					URL:  u,
+					Type: queryContentType(q),
+					Page: page,
+				}
+			}
+		}
 	}
 
-	return collectedData, nil
+	c.pageCrawled(path, found)
+
+	return matched, nil
+}
+
+// resolvePostLinks finds all links to posts on the listing page and resolves
+// them against the page URL, so they can be requested directly.
+func (c *HtmlCrawler) resolvePostLinks(path string) ([]string, error) {
+	const htmlPostLink = ".postContainer a.link"
+
+	base, err := url.Parse(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve post links for %s: %w", path, err)
+	}
+
+	found := make(parser.QueryResult)
+	err = c.fetchPage(path, parser.QueryAttrMap{htmlPostLink: "href"}, found)
+	if err != nil {
+		return nil, err
+	}
+
+	links := make([]string, 0, len(found))
+	for l := range found {
+		u, err := base.Parse(l)
+		if err != nil {
+			return nil, fmt.Errorf("cannot resolve post link %s: %w", l, err)
+		}
+		links = append(links, u.String())
+	}
+
+	return links, nil
+}
+
+// resolveCommentsLink builds a link to the full comment tree of the post. The
+// comment tree is served separately from the post page and contains lazily
+// loaded comments as well.
+//
+// Example: http://joyreactor.cc/post/123 -> http://joyreactor.cc/post/comments/123
+func resolveCommentsLink(postLink string) (string, error) {
+	u, err := url.Parse(postLink)
+	if err != nil {
+		return "", fmt.Errorf("cannot resolve comments for %s: %w", postLink, err)
+	}
+
+	u.Path = path.Join("/post/comments", path.Base(u.Path))
+
+	return u.String(), nil
 }
 
-func (c *HtmlCrawler) fetch(path string, search []string, qr parser.QueryResult) error {
+func (c *HtmlCrawler) fetchPage(path string, q parser.QueryAttrMap, qr parser.QueryResult) error {
 	body, err := c.Transport.FetchData(path)
 	if err != nil {
 		return err
@@ -77,14 +416,42 @@ func (c *HtmlCrawler) fetch(path string, search []string, qr parser.QueryResult)
 		_ = b.Close()
 	}(body)
 
-	err = c.Parser.FindAttrMap(body, buildQuery(search), qr)
+	before := len(qr)
+
+	err = c.Parser.FindAttrMap(body, q, qr)
 	if err != nil {
 		return fmt.Errorf("cannot apply crawler: %w", err)
 	}
 
+	c.pageCrawled(path, len(qr)-before)
+
 	return nil
 }
 
+// pageCrawled reports the crawled page along with the amount of found links.
+func (c *HtmlCrawler) pageCrawled(path string, found int) {
+	c.Metrics.PageFetched()
+	c.log().Debug("page crawled", "page", path, "found", found)
+	event.Notify(c.Events, event.Event{Type: event.PageCrawled, Page: path, Found: found})
+}
+
+// skipped reports whether the page can be skipped after the error, so the
+// crawl goes on without it. Pages responding with an error status, like
+// deleted posts, are skipped.
+func (c *HtmlCrawler) skipped(path string, err error) bool {
+	var se *StatusError
+	if !errors.As(err, &se) {
+		return false
+	}
+	c.log().Warn("page skipped", "path", path, "status", se.Code)
+
+	return true
+}
+
+func (c *HtmlCrawler) log() logging.Logger {
+	return logging.OrDiscard(c.Logger)
+}
+
 func (c *HtmlCrawler) resolveMaxPage(path string) (int, error) {
 	const htmlPagination = ".pagination_expanded .current"
 
@@ -109,6 +476,71 @@ func (c *HtmlCrawler) resolveMaxPage(path string) (int, error) {
 	return intPa, nil
 }
 
+// collection gathers content links found in posts and in comments separately,
+// so the origin of each link can be kept in the resulting handler.Source.
+type collection struct {
+	posts    map[string]handler.Source
+	comments map[string]handler.Source
+
+	// latest is the publication time of the latest crawled post including
+	// skipped ones. It's zero if publication times weren't parsed.
+	latest time.Time
+}
+
+func newCollection() *collection {
+	return &collection{
+		posts:    make(map[string]handler.Source),
+		comments: make(map[string]handler.Source),
+	}
+}
+
+// merge adds sources of the other collection. Sources that are already
+// collected are kept as is.
+func (c *collection) merge(other *collection) {
+	if other.latest.After(c.latest) {
+		c.latest = other.latest
+	}
+
+	for k, v := range other.posts {
+		if _, ok := c.posts[k]; !ok {
+			c.posts[k] = v
+		}
+	}
+	for k, v := range other.comments {
+		if _, ok := c.comments[k]; !ok {
+			c.comments[k] = v
+		}
+	}
+}
+
+// sources returns all collected links sorted by URL. If the same link was
+// found both in a post and in comments it'll be treated as post content.
+func (c *collection) sources() []handler.Source {
+	res := make([]handler.Source, 0, len(c.posts)+len(c.comments))
+	for _, s := range c.posts {
+		res = append(res, s)
+	}
+	for u, s := range c.comments {
+		if _, ok := c.posts[u]; !ok {
+			res = append(res, s)
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].URL < res[j].URL
+	})
+
+	return res
+}
+
+const (
+	// postScope limits queries to the content of posts.
+	postScope = ".post_content"
+
+	// commentScope limits queries to the content of comments.
+	commentScope = ".comment"
+)
+
 // query represents a search query that will be applied against the parser.
 // Each query has a query itself to find corresponding elements, its attr,
 // content of which should be returned, contentType marks a type of content
@@ -120,19 +552,19 @@ type query struct {
 }
 
 var queries = []query{
-	{"image", ".post_content .image > img", "src"},
-	{"image", ".post_content .image > a", "href"},
-	{"gif", ".post_content .video_gif_source", "href"},
-	{"mp4", ".post_content .video_gif source[type='video/mp4']", "src"},
-	{"webm", ".post_content .video_gif source[type='video/webm']", "src"},
+	{"image", ".image > img", "src"},
+	{"image", ".image > a", "href"},
+	{"gif", ".video_gif_source", "href"},
+	{"mp4", ".video_gif source[type='video/mp4']", "src"},
+	{"webm", ".video_gif source[type='video/webm']", "src"},
 }
 
 // buildQuery builds a parser.QueryAttrMap according to provided search list.
 // The resulting parser.QueryAttrMap will contain only those queries that meet
-// required content types.
+// required content types. Each query is limited by the provided scope.
 //
-// Example: buildQuery([]string{"image", "mp4"}).
-func buildQuery(search []string) parser.QueryAttrMap {
+// Example: buildQuery(postScope, []string{"image", "mp4"}).
+func buildQuery(scope string, search []string) parser.QueryAttrMap {
 	qa := parser.QueryAttrMap{}
 
 	searchMap := make(map[string]struct{}, len(search))
@@ -142,9 +574,21 @@ func buildQuery(search []string) parser.QueryAttrMap {
 
 	for _, sq := range queries {
 		if _, ok := searchMap[sq.contentType]; ok {
-			qa[sq.query] = sq.attr
+			qa[scope+" "+sq.query] = sq.attr
 		}
 	}
 
 	return qa
 }
+
+// queryContentType returns the content type of the query built by buildQuery.
+// An empty string is returned for unknown queries.
+func queryContentType(q string) string {
+	for _, sq := range queries {
+		if strings.HasSuffix(q, " "+sq.query) {
+			return sq.contentType
+		}
+	}
+
+	return ""
+}
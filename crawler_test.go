@@ -4,16 +4,21 @@
 package reactor_crw
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
+	"reactor-crw/event"
+	"reactor-crw/handler"
 	"reactor-crw/parser"
 )
 
@@ -40,6 +45,11 @@ func (m *parserMock) FindAttrMap(r io.Reader, qa parser.QueryAttrMap, qr parser.
 	return args.Error(0)
 }
 
+func (m *parserMock) FindBlocks(r io.Reader, block string, q parser.QueryAttrMap, texts []string) ([]parser.Block, error) {
+	args := m.Called(r, block, q, texts)
+	return args.Get(0).([]parser.Block), args.Error(1)
+}
+
 func TestHtmlCrawler_Fetch(t *testing.T) {
 	path := "https://test.com/test/path"
 
@@ -50,7 +60,7 @@ func TestHtmlCrawler_Fetch(t *testing.T) {
 	{
 		t.Log("When multiple pages requested")
 		{
-			c := &HtmlCrawler{trp, prs, true}
+			c := &HtmlCrawler{Transport: trp, Parser: prs, MultiPage: true}
 
 			rc := ioutil.NopCloser(strings.NewReader(""))
 			trp.On("FetchData", path).Return(rc, nil).Once()
@@ -62,7 +72,7 @@ func TestHtmlCrawler_Fetch(t *testing.T) {
 			prs.On("FindAttrMap", rc, mock.Anything, mock.Anything).
 				Run(func(args mock.Arguments) {
 					qr := args.Get(2).(parser.QueryResult)
-					qr["link_1"] = struct{}{}
+					qr["link_1"] = ".post_content .image > img"
 				}).
 				Return(nil).
 				Once()
@@ -70,20 +80,65 @@ func TestHtmlCrawler_Fetch(t *testing.T) {
 			prs.On("FindAttrMap", rc, mock.Anything, mock.Anything).
 				Run(func(args mock.Arguments) {
 					qr := args.Get(2).(parser.QueryResult)
-					qr["link_2"] = struct{}{}
+					qr["link_2"] = ".post_content .image > img"
 				}).
 				Return(nil).
 				Once()
 
 			res, err := c.Fetch(path, []string{"image"})
 			require.NoErrorf(t, err, "Wasn't expected an error during crawl")
-			require.Equal(t, parser.QueryResult{"link_1": struct{}{}, "link_2": struct{}{}}, res)
+			require.Equal(t, []handler.Source{
+				{URL: "link_1", Type: "image", Page: path + "/1"},
+				{URL: "link_2", Type: "image", Page: path + "/2"},
+			}, res)
+		}
+
+		t.Log("When a range of pages requested")
+		{
+			c := &HtmlCrawler{Transport: trp, Parser: prs, MultiPage: true, FirstPage: 2, LastPage: 3}
+
+			rc := ioutil.NopCloser(strings.NewReader("range"))
+			trp.On("FetchData", path).Return(rc, nil).Once()
+
+			prs.On("FindContent", rc, ".pagination_expanded .current").Return("5", nil).Once()
+			trp.On("FetchData", path+"/2").Return(rc, nil).Once()
+			trp.On("FetchData", path+"/3").Return(rc, nil).Once()
+
+			prs.On("FindAttrMap", rc, mock.Anything, mock.Anything).
+				Run(func(args mock.Arguments) {
+					qr := args.Get(2).(parser.QueryResult)
+					qr["link_3"] = ".post_content .image > img"
+				}).
+				Return(nil).
+				Twice()
+
+			res, err := c.Fetch(path, []string{"image"})
+			require.NoErrorf(t, err, "Wasn't expected an error during crawl")
+			require.Equal(t, []handler.Source{{URL: "link_3", Type: "image", Page: path + "/2"}}, res)
+			trp.AssertExpectations(t)
+		}
+
+		t.Log("When the crawl is cancelled")
+		{
+			c := &HtmlCrawler{Transport: trp, Parser: prs, MultiPage: true}
+			cancelled := path + "/cancelled"
+
+			rc := ioutil.NopCloser(strings.NewReader("cancelled"))
+			trp.On("FetchData", cancelled).Return(rc, nil).Once()
+			prs.On("FindContent", rc, ".pagination_expanded .current").Return("3", nil).Once()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			_, err := c.FetchContext(ctx, cancelled, []string{"image"})
+			require.ErrorIs(t, err, context.Canceled)
+			trp.AssertNotCalled(t, "FetchData", cancelled+"/1")
 		}
 
 		t.Log("When parser returned an error")
 		{
 			expectedErr := errors.New("error")
-			c := &HtmlCrawler{trp, prs, false}
+			c := &HtmlCrawler{Transport: trp, Parser: prs}
 
 			rc := ioutil.NopCloser(strings.NewReader(""))
 			trp.On("FetchData", path).Return(rc, nil).Once()
@@ -99,7 +154,7 @@ func TestHtmlCrawler_Fetch(t *testing.T) {
 		t.Log("When transport returned an error")
 		{
 			expectedErr := errors.New("error")
-			c := &HtmlCrawler{trp, prs, false}
+			c := &HtmlCrawler{Transport: trp, Parser: prs}
 
 			rc := ioutil.NopCloser(strings.NewReader(""))
 			trp.On("FetchData", path).Return(rc, expectedErr).Once()
@@ -110,7 +165,7 @@ func TestHtmlCrawler_Fetch(t *testing.T) {
 
 		t.Log("When single page requested")
 		{
-			c := &HtmlCrawler{trp, prs, false}
+			c := &HtmlCrawler{Transport: trp, Parser: prs}
 
 			rc := ioutil.NopCloser(strings.NewReader(""))
 			trp.On("FetchData", path).Return(rc, nil).Once()
@@ -118,14 +173,234 @@ func TestHtmlCrawler_Fetch(t *testing.T) {
 			prs.On("FindAttrMap", rc, mock.Anything, mock.Anything).
 				Run(func(args mock.Arguments) {
 					qr := args.Get(2).(parser.QueryResult)
-					qr["link_1"] = struct{}{}
+					qr["link_1"] = ".post_content .image > img"
 				}).
 				Return(nil).
 				Once()
 
 			res, err := c.Fetch(path, nil)
 			require.NoErrorf(t, err, "Wasn't expected an error during crawl")
-			require.Equal(t, parser.QueryResult{"link_1": struct{}{}}, res)
+			require.Equal(t, []handler.Source{{URL: "link_1", Type: "image", Page: path}}, res)
+		}
+
+		t.Log("When page events are requested")
+		{
+			l := &listenerMock{}
+			c := &HtmlCrawler{Transport: trp, Parser: prs, Events: l}
+
+			rc := ioutil.NopCloser(strings.NewReader("events"))
+			trp.On("FetchData", path).Return(rc, nil).Once()
+
+			prs.On("FindAttrMap", rc, mock.Anything, mock.Anything).
+				Run(func(args mock.Arguments) {
+					qr := args.Get(2).(parser.QueryResult)
+					qr["link_1"] = ".post_content .image > img"
+					qr["link_2"] = ".post_content .image > img"
+				}).
+				Return(nil).
+				Once()
+
+			_, err := c.Fetch(path, []string{"image"})
+			require.NoErrorf(t, err, "Wasn't expected an error during crawl")
+			require.Len(t, l.events, 1)
+			require.Equal(t, event.PageCrawled, l.events[0].Type)
+			require.Equal(t, path, l.events[0].Page)
+			require.Equal(t, 2, l.events[0].Found)
+		}
+
+		t.Log("When posts should be followed from the listing page")
+		{
+			c := &HtmlCrawler{Transport: trp, Parser: prs, FollowPosts: true, MaxWorkers: 2}
+
+			rc := ioutil.NopCloser(strings.NewReader(""))
+			trp.On("FetchData", path).Return(rc, nil).Once()
+
+			prs.On("FindAttrMap", rc, parser.QueryAttrMap{".postContainer a.link": "href"}, mock.Anything).
+				Run(func(args mock.Arguments) {
+					qr := args.Get(2).(parser.QueryResult)
+					qr["/post/1"] = ".postContainer a.link"
+					qr["https://test.com/post/2"] = ".postContainer a.link"
+				}).
+				Return(nil).
+				Once()
+
+			firstRc := ioutil.NopCloser(strings.NewReader("post_1"))
+			secondRc := ioutil.NopCloser(strings.NewReader("post_2"))
+			trp.On("FetchData", "https://test.com/post/1").Return(firstRc, nil).Once()
+			trp.On("FetchData", "https://test.com/post/2").Return(secondRc, nil).Once()
+
+			prs.On("FindAttrMap", firstRc, mock.Anything, mock.Anything).
+				Run(func(args mock.Arguments) {
+					qr := args.Get(2).(parser.QueryResult)
+					qr["link_1"] = ".post_content .image > img"
+				}).
+				Return(nil).
+				Once()
+
+			prs.On("FindAttrMap", secondRc, mock.Anything, mock.Anything).
+				Run(func(args mock.Arguments) {
+					qr := args.Get(2).(parser.QueryResult)
+					qr["link_2"] = ".post_content .video_gif_source"
+				}).
+				Return(nil).
+				Once()
+
+			res, err := c.Fetch(path, []string{"image", "gif"})
+			require.NoErrorf(t, err, "Wasn't expected an error during crawl")
+			require.Equal(t, []handler.Source{
+				{URL: "link_1", Type: "image", Page: "https://test.com/post/1"},
+				{URL: "link_2", Type: "gif", Page: "https://test.com/post/2"},
+			}, res)
+			trp.AssertExpectations(t)
+		}
+
+		t.Log("When a followed post isn't found")
+		{
+			c := &HtmlCrawler{Transport: trp, Parser: prs, FollowPosts: true}
+
+			rc := ioutil.NopCloser(strings.NewReader("missing"))
+			trp.On("FetchData", path).Return(rc, nil).Once()
+
+			prs.On("FindAttrMap", rc, parser.QueryAttrMap{".postContainer a.link": "href"}, mock.Anything).
+				Run(func(args mock.Arguments) {
+					qr := args.Get(2).(parser.QueryResult)
+					qr["/post/3"] = ".postContainer a.link"
+					qr["/post/4"] = ".postContainer a.link"
+				}).
+				Return(nil).
+				Once()
+
+			postRc := ioutil.NopCloser(strings.NewReader("post_4"))
+			trp.On("FetchData", "https://test.com/post/3").
+				Return(ioutil.NopCloser(strings.NewReader("")), &StatusError{URL: "https://test.com/post/3", Code: 404}).
+				Once()
+			trp.On("FetchData", "https://test.com/post/4").Return(postRc, nil).Once()
+
+			prs.On("FindAttrMap", postRc, mock.Anything, mock.Anything).
+				Run(func(args mock.Arguments) {
+					qr := args.Get(2).(parser.QueryResult)
+					qr["link_4"] = ".post_content .image > img"
+				}).
+				Return(nil).
+				Once()
+
+			res, err := c.Fetch(path, []string{"image"})
+			require.NoErrorf(t, err, "Wasn't expected an error for a missing post")
+			require.Equal(t, []handler.Source{
+				{URL: "link_4", Type: "image", Page: "https://test.com/post/4"},
+			}, res)
+			trp.AssertExpectations(t)
+		}
+
+		t.Log("When comments of posts requested")
+		{
+			c := &HtmlCrawler{Transport: trp, Parser: prs, Comments: true}
+
+			rc := ioutil.NopCloser(strings.NewReader(""))
+			trp.On("FetchData", path).Return(rc, nil).Once()
+
+			prs.On("FindAttrMap", rc, parser.QueryAttrMap{".postContainer a.link": "href"}, mock.Anything).
+				Run(func(args mock.Arguments) {
+					qr := args.Get(2).(parser.QueryResult)
+					qr["/post/1"] = ".postContainer a.link"
+				}).
+				Return(nil).
+				Once()
+
+			postRc := ioutil.NopCloser(strings.NewReader("post"))
+			trp.On("FetchData", "https://test.com/post/1").Return(postRc, nil).Once()
+
+			prs.On("FindAttrMap", postRc, parser.QueryAttrMap{".post_content .image > img": "src", ".post_content .image > a": "href"}, mock.Anything).
+				Run(func(args mock.Arguments) {
+					qr := args.Get(2).(parser.QueryResult)
+					qr["link_1"] = ".post_content .image > img"
+				}).
+				Return(nil).
+				Once()
+
+			commentsRc := ioutil.NopCloser(strings.NewReader("comments"))
+			trp.On("FetchData", "https://test.com/post/comments/1").Return(commentsRc, nil).Once()
+
+			prs.On("FindAttrMap", commentsRc, parser.QueryAttrMap{".comment .image > img": "src", ".comment .image > a": "href"}, mock.Anything).
+				Run(func(args mock.Arguments) {
+					qr := args.Get(2).(parser.QueryResult)
+					qr["link_1"] = ".comment .image > img"
+					qr["link_2"] = ".comment .image > a"
+				}).
+				Return(nil).
+				Once()
+
+			res, err := c.Fetch(path, []string{"image"})
+			require.NoErrorf(t, err, "Wasn't expected an error during crawl")
+			require.Equal(t, []handler.Source{
+				{URL: "link_1", Type: "image", Page: "https://test.com/post/1"},
+				{URL: "link_2", Type: "image", Page: "https://test.com/post/1", Comment: true},
+			}, res)
+			trp.AssertExpectations(t)
+		}
+
+		t.Log("When posts are filtered")
+		{
+			c := &HtmlCrawler{
+				Transport:  trp,
+				Parser:     &parser.Html{},
+				PostFilter: PostFilter{ExcludeTags: []string{"Spam"}, MinRating: 5},
+			}
+
+			rc := ioutil.NopCloser(strings.NewReader(`
+				<div class="postContainer">
+					<h2 class="taglist"><a>art</a></h2>
+					<div class="post_content"><div class="image"><img src="link_1"></div></div>
+					<span class="post_rating"><span>12.5</span></span>
+				</div>
+				<div class="postContainer">
+					<h2 class="taglist"><a>art</a><a>spam</a></h2>
+					<div class="post_content"><div class="image"><img src="link_2"></div></div>
+					<span class="post_rating"><span>20</span></span>
+				</div>
+				<div class="postContainer">
+					<h2 class="taglist"><a>art</a></h2>
+					<div class="post_content"><div class="image"><img src="link_3"></div></div>
+					<span class="post_rating"><span>1.2</span></span>
+				</div>
+			`))
+			trp.On("FetchData", path).Return(rc, nil).Once()
+
+			res, err := c.Fetch(path, []string{"image"})
+			require.NoErrorf(t, err, "Wasn't expected an error during crawl")
+			require.Equal(t, []handler.Source{{URL: "link_1", Type: "image", Page: path}}, res)
+		}
+
+		t.Log("When pages are crawled since the date")
+		{
+			since := time.Unix(1700000000, 0)
+			trp := &transportMock{}
+			c := &HtmlCrawler{
+				Transport:  trp,
+				Parser:     &parser.Html{},
+				MultiPage:  true,
+				PostFilter: PostFilter{Since: since},
+			}
+
+			page := func(link string, published time.Time) io.ReadCloser {
+				return ioutil.NopCloser(strings.NewReader(fmt.Sprintf(`
+					<div class="postContainer">
+						<span class="date"><span data-time="%d"></span></span>
+						<div class="post_content"><div class="image"><img src="%s"></div></div>
+					</div>
+				`, published.Unix(), link)))
+			}
+
+			trp.On("FetchData", path).
+				Return(ioutil.NopCloser(strings.NewReader(`<div class="pagination_expanded"><span class="current">3</span></div>`)), nil).
+				Once()
+			trp.On("FetchData", path+"/3").Return(page("link_3", since.Add(time.Hour)), nil).Once()
+			trp.On("FetchData", path+"/2").Return(page("link_2", since.Add(-time.Hour)), nil).Once()
+
+			res, err := c.Fetch(path, []string{"image"})
+			require.NoErrorf(t, err, "Wasn't expected an error during crawl")
+			require.Equal(t, []handler.Source{{URL: "link_3", Type: "image", Page: path + "/3"}}, res)
+			trp.AssertNotCalled(t, "FetchData", path+"/1")
 		}
 	}
 }
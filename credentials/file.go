@@ -0,0 +1,31 @@
+package credentials
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// ReadFile reads a secret from the file by the path. Trailing line breaks are
+// trimmed. The file must not be accessible by other users, otherwise an error
+// is returned, so a leaked secret is noticed. Permissions aren't checked on
+// Windows.
+func ReadFile(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("cannot read secret: %w", err)
+	}
+
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+		return "", fmt.Errorf("secret file %s is accessible by other users, restrict it with chmod 600", path)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("cannot read secret: %w", err)
+	}
+
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
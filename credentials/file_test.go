@@ -0,0 +1,53 @@
+//go:build unit
+// +build unit
+
+package credentials_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"reactor-crw/credentials"
+)
+
+func TestReadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cookie")
+	require.NoError(t, ioutil.WriteFile(path, []byte("secret-cookie\n"), 0600))
+
+	t.Log("Given the need to read a secret from the file.")
+	{
+		t.Log("When the file is accessible by its owner only.")
+		{
+			require.NoError(t, os.Chmod(path, 0600))
+
+			s, err := credentials.ReadFile(path)
+			require.NoError(t, err, "Wasn't expected an error during reading")
+			require.Equal(t, "secret-cookie", s)
+		}
+
+		if runtime.GOOS != "windows" {
+			t.Log("When the file is accessible by other users.")
+			{
+				for _, perm := range []os.FileMode{0644, 0640, 0604} {
+					require.NoError(t, os.Chmod(path, perm))
+
+					s, err := credentials.ReadFile(path)
+					require.Error(t, err, "Expected an error for mode %o", perm)
+					require.Contains(t, err.Error(), "chmod 600")
+					require.Empty(t, s, "The secret shouldn't be returned")
+				}
+			}
+		}
+
+		t.Log("When the file doesn't exist.")
+		{
+			_, err := credentials.ReadFile(filepath.Join(t.TempDir(), "missing"))
+			require.ErrorIs(t, err, os.ErrNotExist)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package credentials
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"golang.org/x/crypto/scrypt"
+
+	"reactor-crw/atomicfile"
+)
+
+// ErrWrongPassphrase returned when the store cannot be decrypted with the
+// provided passphrase.
+var ErrWrongPassphrase = errors.New("wrong passphrase or corrupted credential store")
+
+// Keys of credentials used by the crawler.
+const (
+	KeyCookie   = "cookie"
+	KeyUsername = "username"
+	KeyPassword = "password"
+)
+
+const (
+	saltSize = 16
+
+	// scrypt parameters recommended for interactive logins.
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+	keySize = 32
+)
+
+// file is the content of the store file. The credentials are encrypted with
+// AES-GCM using a key derived from the passphrase with scrypt.
+type file struct {
+	Salt  []byte `json:"salt"`
+	Nonce []byte `json:"nonce"`
+	Data  []byte `json:"data"`
+}
+
+// Store is a local file keeping credentials encrypted with a passphrase.
+type Store struct {
+	path       string
+	passphrase []byte
+	values     map[string]string
+}
+
+// Open decrypts the store file by the path with the passphrase. If the file
+// doesn't exist, the store is empty and the file is created on Save.
+func Open(path, passphrase string) (*Store, error) {
+	s := &Store{
+		path:       path,
+		passphrase: []byte(passphrase),
+		values:     make(map[string]string),
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read credential store: %w", err)
+	}
+
+	var f file
+	if err = json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("cannot parse credential store %s: %w", path, err)
+	}
+
+	gcm, err := newGCM(s.passphrase, f.Salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(f.Nonce) != gcm.NonceSize() {
+		return nil, ErrWrongPassphrase
+	}
+
+	plain, err := gcm.Open(nil, f.Nonce, f.Data, nil)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+
+	if err = json.Unmarshal(plain, &s.values); err != nil {
+		return nil, fmt.Errorf("cannot parse credential store %s: %w", path, err)
+	}
+
+	return s, nil
+}
+
+// Get returns the credential by the key. An empty string is returned if it's
+// not set.
+func (s *Store) Get(key string) string {
+	return s.values[key]
+}
+
+// Set sets the credential by the key. An empty value removes it.
+func (s *Store) Set(key, value string) {
+	if value == "" {
+		delete(s.values, key)
+		return
+	}
+
+	s.values[key] = value
+}
+
+// Keys returns keys of all stored credentials in sorted order.
+func (s *Store) Keys() []string {
+	keys := make([]string, 0, len(s.values))
+	for k := range s.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Save encrypts the credentials with a new salt and writes them to the file.
+// The file is readable only by its owner. It's replaced atomically, so an
+// interrupted save won't corrupt the stored credentials.
+func (s *Store) Save() error {
+	plain, err := json.Marshal(s.values)
+	if err != nil {
+		return err
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err = rand.Read(salt); err != nil {
+		return err
+	}
+
+	gcm, err := newGCM(s.passphrase, salt)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(file{
+		Salt:  salt,
+		Nonce: nonce,
+		Data:  gcm.Seal(nil, nonce, plain, nil),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = atomicfile.Write(s.path, bytes.NewReader(data), 0600); err != nil {
+		return fmt.Errorf("cannot save credential store %s: %w", s.path, err)
+	}
+
+	return nil
+}
+
+func newGCM(passphrase, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key(passphrase, salt, scryptN, scryptR, scryptP, keySize)
+	if err != nil {
+		return nil, fmt.Errorf("cannot derive key: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,57 @@
+//go:build unit
+// +build unit
+
+package credentials_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"reactor-crw/credentials"
+)
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.json")
+
+	t.Log("Given the need to keep credentials encrypted.")
+	{
+		t.Log("When credentials are saved.")
+		{
+			s, err := credentials.Open(path, "passphrase")
+			require.NoError(t, err, "Wasn't expected an error for a missing store")
+
+			s.Set(credentials.KeyCookie, "secret-cookie")
+			s.Set(credentials.KeyUsername, "user")
+			require.NoError(t, s.Save())
+
+			data, _ := ioutil.ReadFile(path)
+			require.False(t, strings.Contains(string(data), "secret-cookie"), "Expected the cookie to be encrypted")
+
+			files, _ := ioutil.ReadDir(filepath.Dir(path))
+			require.Len(t, files, 1, "Wasn't expected temporary files to be left")
+			if runtime.GOOS != "windows" {
+				require.Equal(t, os.FileMode(0600), files[0].Mode().Perm())
+			}
+		}
+
+		t.Log("When the store is opened with the same passphrase.")
+		{
+			s, err := credentials.Open(path, "passphrase")
+			require.NoError(t, err, "Wasn't expected an error during decryption")
+			require.Equal(t, "secret-cookie", s.Get(credentials.KeyCookie))
+			require.Equal(t, []string{credentials.KeyCookie, credentials.KeyUsername}, s.Keys())
+		}
+
+		t.Log("When the store is opened with another passphrase.")
+		{
+			_, err := credentials.Open(path, "wrong")
+			require.ErrorIs(t, err, credentials.ErrWrongPassphrase)
+		}
+	}
+}
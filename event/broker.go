@@ -0,0 +1,75 @@
+package event
+
+import "sync"
+
+// subscriberBuffer limits the amount of events kept for a slow subscriber.
+const subscriberBuffer = 256
+
+// Broker delivers events to multiple subscribers. Events are never blocked by
+// subscribers: if a subscriber doesn't keep up, events are dropped for it.
+type Broker struct {
+	mu     sync.Mutex
+	subs   map[chan Event]struct{}
+	closed bool
+}
+
+// NewBroker creates a new *Broker without subscribers.
+func NewBroker() *Broker {
+	return &Broker{subs: make(map[chan Event]struct{})}
+}
+
+// Notify implements Listener.
+func (b *Broker) Notify(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel of events and a function that cancels the
+// subscription. The channel is closed when the broker is closed. If the
+// broker is already closed the returned channel is closed as well.
+func (b *Broker) Subscribe() (<-chan Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan Event, subscriberBuffer)
+	if b.closed {
+		close(ch)
+		return ch, func() {}
+	}
+
+	b.subs[ch] = struct{}{}
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		if _, ok := b.subs[ch]; ok {
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// Close closes channels of all subscribers. Events sent after Close are
+// dropped.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+	b.closed = true
+
+	for ch := range b.subs {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
@@ -0,0 +1,52 @@
+//go:build unit
+// +build unit
+
+package event_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"reactor-crw/event"
+)
+
+func TestBroker(t *testing.T) {
+	b := event.NewBroker()
+
+	t.Log("Given the need to deliver events to multiple subscribers.")
+	{
+		first, _ := b.Subscribe()
+		second, cancel := b.Subscribe()
+
+		t.Log("When an event is sent.")
+		{
+			b.Notify(event.Event{Type: event.ItemDone})
+
+			require.Equal(t, event.ItemDone, (<-first).Type)
+			require.Equal(t, event.ItemDone, (<-second).Type)
+		}
+
+		t.Log("When a subscription is cancelled.")
+		{
+			cancel()
+			b.Notify(event.Event{Type: event.ItemFailed})
+
+			require.Equal(t, event.ItemFailed, (<-first).Type)
+			_, ok := <-second
+			require.False(t, ok, "Cancelled subscription should be closed")
+		}
+
+		t.Log("When the broker is closed.")
+		{
+			b.Close()
+
+			_, ok := <-first
+			require.False(t, ok, "Subscription should be closed with the broker")
+
+			late, _ := b.Subscribe()
+			_, ok = <-late
+			require.False(t, ok, "Subscription to a closed broker should be closed")
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package event
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Type describes a type of event.
+type Type string
+
+const (
+	// PageCrawled is sent when a page was crawled and its links were collected.
+	PageCrawled Type = "page_crawled"
+
+	// ItemStarted is sent when a content source is passed to the handler.
+	ItemStarted Type = "item_started"
+
+	// ItemDone is sent when a content source was processed successfully.
+	ItemDone Type = "item_done"
+
+	// ItemFailed is sent when a content source failed to process.
+	ItemFailed Type = "item_failed"
+
+	// RunFinished is sent when the client finished its work.
+	RunFinished Type = "run_finished"
+)
+
+// Event describes a single step of the crawler client work. Only fields that
+// are relevant to the event type are set.
+type Event struct {
+	Type Type      `json:"type"`
+	Time time.Time `json:"time"`
+
+	// Page contains the URL of the crawled page.
+	Page string `json:"page,omitempty"`
+
+	// URL contains the URL of the content source.
+	URL string `json:"url,omitempty"`
+
+	// Comment marks content sources found in post comments.
+	Comment bool `json:"comment,omitempty"`
+
+	// Found contains the amount of links found on the page or by the whole run.
+	Found int `json:"found,omitempty"`
+
+	// Failed contains the amount of content sources failed during the run.
+	Failed int `json:"failed,omitempty"`
+
+	// Error contains the error message of a failed item or run.
+	Error string `json:"error,omitempty"`
+}
+
+// Listener receives events. Implementations must be safe for concurrent use.
+type Listener interface {
+	Notify(e Event)
+}
+
+// Notify sends the event to the listener if it's set. The event time is set
+// to the current time.
+func Notify(l Listener, e Event) {
+	if l == nil {
+		return
+	}
+
+	e.Time = time.Now()
+	l.Notify(e)
+}
+
+// JSONWriter writes events as newline delimited JSON.
+type JSONWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONWriter creates a new *JSONWriter that writes events to w.
+func NewJSONWriter(w io.Writer) *JSONWriter {
+	return &JSONWriter{enc: json.NewEncoder(w)}
+}
+
+// Notify implements Listener.
+func (w *JSONWriter) Notify(e Event) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	_ = w.enc.Encode(e)
+}
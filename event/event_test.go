@@ -0,0 +1,51 @@
+//go:build unit
+// +build unit
+
+package event_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"reactor-crw/event"
+)
+
+func TestJSONWriter_Notify(t *testing.T) {
+	t.Log("Given the need to write events as newline delimited JSON.")
+	{
+		t.Log("When multiple events are sent.")
+		{
+			buf := &bytes.Buffer{}
+			w := event.NewJSONWriter(buf)
+
+			event.Notify(w, event.Event{Type: event.ItemDone, URL: "url"})
+			event.Notify(w, event.Event{Type: event.RunFinished, Found: 1})
+
+			var events []event.Event
+			s := bufio.NewScanner(buf)
+			for s.Scan() {
+				var e event.Event
+				require.NoError(t, json.Unmarshal(s.Bytes(), &e), "Each line should be a valid JSON")
+				events = append(events, e)
+			}
+
+			require.Len(t, events, 2)
+			require.Equal(t, event.ItemDone, events[0].Type)
+			require.Equal(t, "url", events[0].URL)
+			require.False(t, events[0].Time.IsZero(), "Event time should be set")
+			require.Equal(t, event.RunFinished, events[1].Type)
+			require.Equal(t, 1, events[1].Found)
+		}
+
+		t.Log("When listener isn't set.")
+		{
+			require.NotPanics(t, func() {
+				event.Notify(nil, event.Event{Type: event.ItemDone})
+			})
+		}
+	}
+}
@@ -1,8 +1,24 @@
 package handler
 
+// Source describes a single content source found by a crawler.
+type Source struct {
+	// URL is a direct link to the content.
+	URL string
+
+	// Type is the content type the URL was found by. Example: image, mp4.
+	Type string
+
+	// Page is the URL of the page the content was found on.
+	Page string
+
+	// Comment marks content that was found in post comments rather than in
+	// the post itself.
+	Comment bool
+}
+
 // ContentHandler defines an interface for handling content sources that are
 // represented as URLs. When the handler will finish its job it should notify
 // the progress. Each error should be sent to the errors channel.
 type ContentHandler interface {
-	Process(url string, progress chan<- int, errors chan<- error)
+	Process(s Source, progress chan<- int, errors chan<- error)
 }
@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+
+	"reactor-crw/atomicfile"
+)
+
+// Index keeps track of content sources that were already handled. A single
+// Index can be shared between handlers of different crawls, so the same
+// content won't be processed twice within one process. The index can be
+// saved to a file and loaded back to keep the state between runs.
+type Index struct {
+	mu   sync.Mutex
+	seen map[string]struct{}
+}
+
+// NewIndex creates a new empty *Index.
+func NewIndex() *Index {
+	return &Index{seen: make(map[string]struct{})}
+}
+
+// LoadIndex loads the index previously saved by Index.Save. If the file
+// doesn't exist a new empty index is returned.
+func LoadIndex(path string) (*Index, error) {
+	i := NewIndex()
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return i, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read index %s: %w", path, err)
+	}
+
+	var urls []string
+	err = json.Unmarshal(data, &urls)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse index %s: %w", path, err)
+	}
+
+	for _, u := range urls {
+		i.seen[u] = struct{}{}
+	}
+
+	return i, nil
+}
+
+// Save writes all seen sources to the file. The file is replaced atomically,
+// so an interrupted save won't corrupt the previous state. Missing parent
+// directories are created.
+func (i *Index) Save(path string) error {
+	i.mu.Lock()
+	urls := make([]string, 0, len(i.seen))
+	for u := range i.seen {
+		urls = append(urls, u)
+	}
+	i.mu.Unlock()
+
+	sort.Strings(urls)
+
+	data, err := json.Marshal(urls)
+	if err != nil {
+		return fmt.Errorf("cannot encode index: %w", err)
+	}
+
+	if err = atomicfile.Write(path, bytes.NewReader(data), 0600); err != nil {
+		return fmt.Errorf("cannot save index %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// Add marks the source URL as seen. It reports whether the URL wasn't seen
+// before.
+func (i *Index) Add(url string) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if _, ok := i.seen[url]; ok {
+		return false
+	}
+	i.seen[url] = struct{}{}
+
+	return true
+}
+
+// Remove forgets the source URL, so it can be processed again.
+func (i *Index) Remove(url string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	delete(i.seen, url)
+}
+
+// Len returns the amount of seen sources.
+func (i *Index) Len() int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return len(i.seen)
+}
+
+// Wrap returns a ContentHandler that passes only unseen sources to the
+// provided handler. Already seen sources are skipped but still reported to
+// the progress channel. Sources that failed to process are removed from the
+// index, so they will be processed again next time.
+func (i *Index) Wrap(h ContentHandler) ContentHandler {
+	return &dedupHandler{index: i, next: h}
+}
+
+type dedupHandler struct {
+	index *Index
+	next  ContentHandler
+}
+
+// Process implements ContentHandler.
+func (d *dedupHandler) Process(s Source, progress chan<- int, errors chan<- error) {
+	if !d.index.Add(s.URL) {
+		progress <- 1
+		return
+	}
+
+	failed := false
+	proxy := make(chan error)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		for err := range proxy {
+			failed = true
+			errors <- err
+		}
+	}()
+
+	d.next.Process(s, progress, proxy)
+	close(proxy)
+	<-done
+
+	if failed {
+		d.index.Remove(s.URL)
+	}
+}
@@ -0,0 +1,126 @@
+//go:build unit
+// +build unit
+
+package handler_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"reactor-crw/handler"
+)
+
+type contentHandlerMock struct {
+	mock.Mock
+}
+
+func (m *contentHandlerMock) Process(s handler.Source, progress chan<- int, errors chan<- error) {
+	args := m.Called(s)
+	if err := args.Error(0); err != nil {
+		errors <- err
+	}
+	progress <- 1
+}
+
+func TestIndex_Wrap(t *testing.T) {
+	idx := handler.NewIndex()
+
+	first := &contentHandlerMock{}
+	second := &contentHandlerMock{}
+
+	p := make(chan int, 1)
+	e := make(chan error, 1)
+
+	t.Log("Given the need to skip already processed sources.")
+	{
+		t.Log("When the source wasn't seen before.")
+		{
+			first.On("Process", handler.Source{URL: "url"}).Return(nil).Once()
+
+			idx.Wrap(first).Process(handler.Source{URL: "url"}, p, e)
+			<-p
+
+			first.AssertExpectations(t)
+		}
+
+		t.Log("When the source was seen by another handler.")
+		{
+			idx.Wrap(second).Process(handler.Source{URL: "url"}, p, e)
+			<-p
+
+			second.AssertNotCalled(t, "Process", mock.Anything)
+			require.Equal(t, 1, idx.Len())
+		}
+
+		t.Log("When the source failed to process.")
+		{
+			second.On("Process", handler.Source{URL: "failed"}).Return(errors.New("error")).Once()
+
+			idx.Wrap(second).Process(handler.Source{URL: "failed"}, p, e)
+			require.Error(t, <-e, "Expected an error to be passed through")
+			<-p
+
+			require.True(t, idx.Add("failed"), "Failed source should be removed from the index")
+		}
+	}
+}
+
+func TestIndex_Save(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "index")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	path := filepath.Join(dir, "index.json")
+
+	t.Log("Given the need to keep the index between runs.")
+	{
+		t.Log("When the index file doesn't exist yet.")
+		{
+			idx, err := handler.LoadIndex(path)
+			require.NoError(t, err, "Wasn't expected an error on loading missing index")
+			require.Equal(t, 0, idx.Len())
+		}
+
+		t.Log("When the index was saved.")
+		{
+			idx := handler.NewIndex()
+			idx.Add("url_1")
+			idx.Add("url_2")
+			require.NoError(t, idx.Save(path), "Wasn't expected an error on saving index")
+
+			loaded, err := handler.LoadIndex(path)
+			require.NoError(t, err, "Wasn't expected an error on loading index")
+			require.Equal(t, 2, loaded.Len())
+			require.False(t, loaded.Add("url_1"), "Loaded index should contain saved sources")
+		}
+
+		t.Log("When the directory of the index file doesn't exist yet.")
+		{
+			nested := filepath.Join(dir, "state", "jobs", "index.json")
+
+			idx := handler.NewIndex()
+			idx.Add("url_1")
+			require.NoError(t, idx.Save(nested), "Wasn't expected an error on saving index to a new directory")
+
+			loaded, err := handler.LoadIndex(nested)
+			require.NoError(t, err, "Wasn't expected an error on loading index")
+			require.Equal(t, 1, loaded.Len())
+		}
+
+		t.Log("When the index file is corrupted.")
+		{
+			require.NoError(t, ioutil.WriteFile(path, []byte("{"), 0600))
+
+			_, err := handler.LoadIndex(path)
+			require.Error(t, err, "Expected an error on loading corrupted index")
+		}
+	}
+}
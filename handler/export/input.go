@@ -0,0 +1,128 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"reactor-crw"
+	"reactor-crw/handler"
+	"reactor-crw/handler/fs"
+)
+
+// Tool describes a downloader an input file is written for.
+type Tool string
+
+const (
+	// Aria2 writes aria2c input files. Each URL is followed by its output
+	// folder, file name and request headers.
+	Aria2 Tool = "aria2"
+
+	// Wget writes one URL per line as expected by wget -i. Wget doesn't
+	// support per-URL options within input files, so headers should be
+	// passed with --header flags instead.
+	Wget Tool = "wget"
+)
+
+// ParseTool converts a downloader name to Tool.
+func ParseTool(s string) (Tool, error) {
+	switch t := Tool(strings.ToLower(s)); t {
+	case Aria2, Wget:
+		return t, nil
+	default:
+		return "", fmt.Errorf("unknown downloader: %s", s)
+	}
+}
+
+// Handler defines ContentHandler implementation that writes content sources to
+// an input file of a downloader instead of downloading them. This way crawling
+// and downloading can be split across machines. It's safe for concurrent use.
+type Handler struct {
+	// NameResolver resolves output file names the same way fs.FileSaver
+	// does. Names are written to aria2 input files only.
+	fs.NameResolver
+
+	// Dir is the folder content should be saved to by the downloader. It's
+	// written to aria2 input files only.
+	Dir string
+
+	// Headers are sent by the downloader with each request. Headers with
+	// empty values are skipped. They're written to aria2 input files only.
+	Headers reactor_crw.Headers
+
+	mu   sync.Mutex
+	w    io.Writer
+	tool Tool
+}
+
+// NewHandler creates a new *Handler that writes an input file for the tool
+// to w.
+func NewHandler(w io.Writer, t Tool) *Handler {
+	return &Handler{
+		w:    w,
+		tool: t,
+	}
+}
+
+// Process writes the content source to the input file.
+func (h *Handler) Process(s handler.Source, progress chan<- int, e chan<- error) {
+	defer func() {
+		progress <- 1
+	}()
+
+	entry, err := h.entry(s)
+	if err != nil {
+		e <- err
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, err = io.WriteString(h.w, entry); err != nil {
+		e <- fmt.Errorf("cannot write %s to input file: %w", s.URL, err)
+	}
+}
+
+func (h *Handler) entry(s handler.Source) (string, error) {
+	if h.tool == Wget {
+		return oneLine(s.URL) + "\n", nil
+	}
+
+	name, err := h.Resolve(s)
+	if err != nil {
+		return "", err
+	}
+
+	b := strings.Builder{}
+	b.WriteString(oneLine(s.URL) + "\n")
+	if h.Dir != "" {
+		b.WriteString("  dir=" + oneLine(h.Dir) + "\n")
+	}
+	b.WriteString("  out=" + oneLine(name) + "\n")
+
+	keys := make([]string, 0, len(h.Headers))
+	for k, v := range h.Headers {
+		if v != "" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		b.WriteString("  header=" + oneLine(k) + ": " + oneLine(h.Headers[k]) + "\n")
+	}
+
+	return b.String(), nil
+}
+
+// lineBreaks removes line breaks, so a value cannot add options to the input
+// file.
+var lineBreaks = strings.NewReplacer("\r", "", "\n", "")
+
+// oneLine returns the value without line breaks.
+func oneLine(v string) string {
+	return lineBreaks.Replace(v)
+}
@@ -0,0 +1,103 @@
+//go:build unit
+// +build unit
+
+package export_test
+
+import (
+	"bytes"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/require"
+
+	"reactor-crw"
+	"reactor-crw/handler"
+	"reactor-crw/handler/export"
+)
+
+func process(h *export.Handler, s handler.Source) error {
+	progress := make(chan int, 1)
+	errs := make(chan error, 1)
+
+	h.Process(s, progress, errs)
+	<-progress
+
+	select {
+	case err := <-errs:
+		return err
+	default:
+		return nil
+	}
+}
+
+func TestHandler_Process(t *testing.T) {
+	t.Log("Given the need to write content sources to downloader input files.")
+	{
+		t.Log("When an aria2 input file is written.")
+		{
+			buf := &bytes.Buffer{}
+			h := export.NewHandler(buf, export.Aria2)
+			h.Dir = "/data/tag"
+			h.CommentsFolder = "comments"
+			h.Headers = reactor_crw.Headers{"Referer": "http://test.com/", "Cookie": "", "User-Agent": "agent"}
+
+			require.NoError(t, process(h, handler.Source{URL: "http://test.com/pics/a.jpeg"}))
+			require.NoError(t, process(h, handler.Source{URL: "http://test.com/pics/b.gif", Comment: true}))
+
+			expected := "http://test.com/pics/a.jpeg\n" +
+				"  dir=/data/tag\n" +
+				"  out=a.jpeg\n" +
+				"  header=Referer: http://test.com/\n" +
+				"  header=User-Agent: agent\n" +
+				"http://test.com/pics/b.gif\n" +
+				"  dir=/data/tag\n" +
+				"  out=comments/b.gif\n" +
+				"  header=Referer: http://test.com/\n" +
+				"  header=User-Agent: agent\n"
+			require.Equal(t, expected, buf.String())
+		}
+
+		t.Log("When header values contain line breaks.")
+		{
+			buf := &bytes.Buffer{}
+			h := export.NewHandler(buf, export.Aria2)
+			h.Headers = reactor_crw.Headers{"Cookie": "a=1\r\n  out=../../evil\n"}
+
+			require.NoError(t, process(h, handler.Source{URL: "http://test.com/pics/a.jpeg"}))
+
+			expected := "http://test.com/pics/a.jpeg\n" +
+				"  out=a.jpeg\n" +
+				"  header=Cookie: a=1  out=../../evil\n"
+			require.Equal(t, expected, buf.String())
+		}
+
+		t.Log("When a wget input file is written.")
+		{
+			buf := &bytes.Buffer{}
+			h := export.NewHandler(buf, export.Wget)
+			h.Headers = reactor_crw.Headers{"Referer": "http://test.com/"}
+
+			require.NoError(t, process(h, handler.Source{URL: "http://test.com/pics/a.jpeg"}))
+			require.Equal(t, "http://test.com/pics/a.jpeg\n", buf.String())
+		}
+
+		t.Log("When a wget URL contains line breaks.")
+		{
+			buf := &bytes.Buffer{}
+			h := export.NewHandler(buf, export.Wget)
+
+			require.NoError(t, process(h, handler.Source{URL: "http://test.com/a.jpeg\nhttp://evil.com/b"}))
+			require.Equal(t, "http://test.com/a.jpeghttp://evil.com/b\n", buf.String())
+		}
+
+		t.Log("When the file name cannot be resolved.")
+		{
+			buf := &bytes.Buffer{}
+			h := export.NewHandler(buf, export.Aria2)
+			h.NameTemplate = template.Must(template.New("name").Parse("{{.Unknown}}"))
+
+			require.Error(t, process(h, handler.Source{URL: "http://test.com/pics/a.jpeg"}))
+			require.Empty(t, buf.String(), "Nothing should be written on error")
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"reactor-crw/handler"
+)
+
+// Format describes a format of the exported list of content sources.
+type Format string
+
+const (
+	// FormatText writes one URL per line. Such lists can be passed directly to
+	// downloaders like aria2c or wget.
+	FormatText Format = "txt"
+
+	// FormatJSON writes a JSON array of records.
+	FormatJSON Format = "json"
+
+	// FormatCSV writes records as CSV with a header line.
+	FormatCSV Format = "csv"
+)
+
+// FormatOf returns the list format by the extension of the file name. Unknown
+// extensions are treated as FormatText.
+func FormatOf(name string) Format {
+	switch f := Format(strings.ToLower(strings.TrimPrefix(filepath.Ext(name), "."))); f {
+	case FormatJSON, FormatCSV:
+		return f
+	default:
+		return FormatText
+	}
+}
+
+// record describes a single content source in exported lists.
+type record struct {
+	URL     string `json:"url"`
+	Type    string `json:"type"`
+	Page    string `json:"page"`
+	Comment bool   `json:"comment"`
+}
+
+// WriteList writes the sources to w in the format.
+func WriteList(w io.Writer, sources []handler.Source, f Format) error {
+	var err error
+
+	switch f {
+	case FormatJSON:
+		err = writeJSON(w, sources)
+	case FormatCSV:
+		err = writeCSV(w, sources)
+	default:
+		err = writeText(w, sources)
+	}
+
+	if err != nil {
+		return fmt.Errorf("cannot write list: %w", err)
+	}
+
+	return nil
+}
+
+func writeText(w io.Writer, sources []handler.Source) error {
+	for _, s := range sources {
+		if _, err := fmt.Fprintln(w, s.URL); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeJSON(w io.Writer, sources []handler.Source) error {
+	records := make([]record, 0, len(sources))
+	for _, s := range sources {
+		records = append(records, record{URL: s.URL, Type: s.Type, Page: s.Page, Comment: s.Comment})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(records)
+}
+
+func writeCSV(w io.Writer, sources []handler.Source) error {
+	cw := csv.NewWriter(w)
+
+	_ = cw.Write([]string{"url", "type", "page", "comment"})
+	for _, s := range sources {
+		_ = cw.Write([]string{s.URL, s.Type, s.Page, strconv.FormatBool(s.Comment)})
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
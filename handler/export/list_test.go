@@ -0,0 +1,66 @@
+//go:build unit
+// +build unit
+
+package export_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"reactor-crw/handler"
+	"reactor-crw/handler/export"
+)
+
+func TestWriteList(t *testing.T) {
+	sources := []handler.Source{
+		{URL: "http://test.com/a.jpeg", Type: "image", Page: "http://test.com/post/1"},
+		{URL: "http://test.com/b.mp4", Type: "mp4", Page: "http://test.com/post/2", Comment: true},
+	}
+
+	t.Log("Given the need to export found content sources.")
+	{
+		t.Log("When the list is written as text.")
+		{
+			buf := &bytes.Buffer{}
+			require.NoError(t, export.WriteList(buf, sources, export.FormatText))
+			require.Equal(t, "http://test.com/a.jpeg\nhttp://test.com/b.mp4\n", buf.String())
+		}
+
+		t.Log("When the list is written as CSV.")
+		{
+			buf := &bytes.Buffer{}
+			require.NoError(t, export.WriteList(buf, sources, export.FormatCSV))
+			require.Equal(t,
+				"url,type,page,comment\n"+
+					"http://test.com/a.jpeg,image,http://test.com/post/1,false\n"+
+					"http://test.com/b.mp4,mp4,http://test.com/post/2,true\n",
+				buf.String(),
+			)
+		}
+
+		t.Log("When the list is written as JSON.")
+		{
+			buf := &bytes.Buffer{}
+			require.NoError(t, export.WriteList(buf, sources, export.FormatJSON))
+
+			var records []map[string]interface{}
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &records))
+			require.Len(t, records, 2)
+			require.Equal(t, "mp4", records[1]["type"])
+			require.Equal(t, true, records[1]["comment"])
+		}
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	t.Log("Given the need to resolve the list format by the file name.")
+	{
+		require.Equal(t, export.FormatJSON, export.FormatOf("list.JSON"))
+		require.Equal(t, export.FormatCSV, export.FormatOf("/tmp/list.csv"))
+		require.Equal(t, export.FormatText, export.FormatOf("urls.txt"))
+		require.Equal(t, export.FormatText, export.FormatOf("urls"))
+	}
+}
@@ -0,0 +1,249 @@
+package filter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"reactor-crw"
+	"reactor-crw/handler"
+	"reactor-crw/logging"
+)
+
+// sniffLen is the amount of bytes used to detect the content type.
+const sniffLen = 512
+
+// Rules describe which content should be saved. Zero values disable the
+// corresponding checks.
+type Rules struct {
+	// MinSize and MaxSize limit the content size in bytes.
+	MinSize int64
+	MaxSize int64
+
+	// Extensions lists allowed extensions of content URLs without the dot.
+	// Example: jpeg, png. The jpg and jpeg extensions are treated as the same.
+	Extensions []string
+
+	// MIMETypes lists allowed MIME types of the content. A type may contain
+	// a wildcard subtype. Example: image/*, video/mp4.
+	MIMETypes []string
+
+	// ExcludeComments skips content found in post comments.
+	ExcludeComments bool
+}
+
+// Empty reports whether there are no rules to check.
+func (r Rules) Empty() bool {
+	return r.MinSize <= 0 && r.MaxSize <= 0 && len(r.Extensions) == 0 && len(r.MIMETypes) == 0 && !r.ExcludeComments
+}
+
+// probed reports whether the rules require information about the content.
+func (r Rules) probed() bool {
+	return r.MinSize > 0 || r.MaxSize > 0 || len(r.MIMETypes) > 0
+}
+
+// checkURL returns the reason the content URL doesn't match the rules or an
+// empty string if it does.
+func (r Rules) checkURL(rawURL string) string {
+	if len(r.Extensions) == 0 {
+		return ""
+	}
+
+	p := rawURL
+	if u, err := url.Parse(rawURL); err == nil {
+		p = u.Path
+	}
+
+	ext := normalizeExt(path.Ext(p))
+	for _, e := range r.Extensions {
+		if normalizeExt(e) == ext {
+			return ""
+		}
+	}
+
+	return fmt.Sprintf("extension %q isn't allowed", ext)
+}
+
+// checkInfo returns the reason the content doesn't match the rules or an
+// empty string if it does. Unknown type or length aren't checked, they're
+// checked while the content is downloaded.
+func (r Rules) checkInfo(info reactor_crw.ContentInfo) string {
+	if info.Length >= 0 {
+		if r.MinSize > 0 && info.Length < r.MinSize {
+			return fmt.Sprintf("size %d is less than %d", info.Length, r.MinSize)
+		}
+		if r.MaxSize > 0 && info.Length > r.MaxSize {
+			return fmt.Sprintf("size %d is greater than %d", info.Length, r.MaxSize)
+		}
+	}
+
+	if info.Type == "" {
+		return ""
+	}
+
+	return r.checkType(info.Type)
+}
+
+// checkType returns the reason the MIME type doesn't match the rules or an
+// empty string if it does.
+func (r Rules) checkType(contentType string) string {
+	if len(r.MIMETypes) == 0 {
+		return ""
+	}
+
+	t, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		t = contentType
+	}
+
+	for _, m := range r.MIMETypes {
+		if matchMIME(strings.ToLower(m), t) {
+			return ""
+		}
+	}
+
+	return fmt.Sprintf("type %q isn't allowed", t)
+}
+
+func matchMIME(pattern, t string) bool {
+	if pattern == "*/*" || pattern == t {
+		return true
+	}
+
+	return strings.HasSuffix(pattern, "/*") && strings.HasPrefix(t, strings.TrimSuffix(pattern, "*"))
+}
+
+func normalizeExt(ext string) string {
+	ext = strings.ToLower(strings.TrimPrefix(ext, "."))
+	if ext == "jpg" {
+		return "jpeg"
+	}
+
+	return ext
+}
+
+// Filter passes to a handler only content that matches its rules. Extensions
+// are checked by content URLs. Size and MIME type are checked by probing the
+// content with a HEAD request before it's downloaded. As an Inspector, it
+// checks the MIME type of the downloaded content as well.
+type Filter struct {
+	Rules Rules
+
+	// Prober fetches information about the content. If it's nil or doesn't
+	// support probing, size and MIME type aren't checked before the content
+	// is downloaded. Content that fails to be probed is skipped.
+	Prober reactor_crw.Prober
+
+	// Logger receives a record for each skipped source. It's optional.
+	Logger logging.Logger
+}
+
+// Wrap returns a ContentHandler that skips sources not matching the rules and
+// passes the rest to h. Skipped sources are reported as processed without
+// errors.
+func (f *Filter) Wrap(h handler.ContentHandler) handler.ContentHandler {
+	return &filterHandler{filter: f, next: h}
+}
+
+type filterHandler struct {
+	filter *Filter
+	next   handler.ContentHandler
+}
+
+// Process implements handler.ContentHandler.
+func (h *filterHandler) Process(s handler.Source, progress chan<- int, errors chan<- error) {
+	if reason := h.filter.Check(s); reason != "" {
+		h.filter.log().Debug("source skipped", "url", s.URL, "reason", reason)
+		progress <- 1
+		return
+	}
+
+	h.next.Process(s, progress, errors)
+}
+
+// Check returns the reason the source doesn't match the rules or an empty
+// string if it does.
+func (f *Filter) Check(s handler.Source) string {
+	if f.Rules.ExcludeComments && s.Comment {
+		return "content from comments is excluded"
+	}
+
+	if reason := f.Rules.checkURL(s.URL); reason != "" {
+		return reason
+	}
+
+	if !f.Rules.probed() || f.Prober == nil {
+		return ""
+	}
+
+	info, err := f.Prober.Probe(s.URL)
+	if errors.Is(err, reactor_crw.ErrProbeUnsupported) {
+		return ""
+	}
+	if err != nil {
+		f.log().Warn("cannot probe source, it's skipped", "url", s.URL, "error", err)
+		return fmt.Sprintf("cannot probe content: %s", err)
+	}
+
+	return f.Rules.checkInfo(info)
+}
+
+// Inspect detects the MIME type of the content from its beginning and returns
+// the reason the content doesn't match the rules or an empty string if it
+// does. The returned reader yields the whole content.
+func (f *Filter) Inspect(_ handler.Source, r io.Reader) (io.Reader, string) {
+	if len(f.Rules.MIMETypes) == 0 {
+		return r, ""
+	}
+
+	header := make([]byte, sniffLen)
+	n, err := io.ReadFull(r, header)
+	content := io.MultiReader(bytes.NewReader(header[:n]), r)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return content, ""
+	}
+
+	return content, f.Rules.checkType(http.DetectContentType(header[:n]))
+}
+
+func (f *Filter) log() logging.Logger {
+	return logging.OrDiscard(f.Logger)
+}
+
+// ParseSize converts a size like 512, 300K, 10MB or 1.5G to bytes. Units are
+// case-insensitive and are powers of 1024.
+func ParseSize(s string) (int64, error) {
+	units := []struct {
+		suffix string
+		mult   float64
+	}{
+		{"gb", 1 << 30}, {"g", 1 << 30},
+		{"mb", 1 << 20}, {"m", 1 << 20},
+		{"kb", 1 << 10}, {"k", 1 << 10},
+		{"b", 1},
+	}
+
+	v := strings.ToLower(strings.TrimSpace(s))
+	mult := 1.0
+	for _, u := range units {
+		if strings.HasSuffix(v, u.suffix) {
+			v = strings.TrimSpace(strings.TrimSuffix(v, u.suffix))
+			mult = u.mult
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size: %s", s)
+	}
+
+	return int64(n * mult), nil
+}
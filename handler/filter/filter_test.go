@@ -0,0 +1,153 @@
+//go:build unit
+// +build unit
+
+package filter_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"reactor-crw"
+	"reactor-crw/handler"
+	"reactor-crw/handler/filter"
+)
+
+type contentHandlerMock struct {
+	mock.Mock
+}
+
+func (m *contentHandlerMock) Process(s handler.Source, progress chan<- int, _ chan<- error) {
+	m.Called(s)
+	progress <- 1
+}
+
+type proberMock struct {
+	mock.Mock
+}
+
+func (m *proberMock) Probe(url string) (reactor_crw.ContentInfo, error) {
+	args := m.Called(url)
+	return args.Get(0).(reactor_crw.ContentInfo), args.Error(1)
+}
+
+func process(h handler.ContentHandler, url string) {
+	p := make(chan int, 1)
+	h.Process(handler.Source{URL: url}, p, make(chan error, 1))
+	<-p
+}
+
+func TestFilter_Wrap(t *testing.T) {
+	t.Log("Given the need to skip content that doesn't match the rules.")
+	{
+		t.Log("When extensions are limited.")
+		{
+			ch := &contentHandlerMock{}
+			ch.On("Process", handler.Source{URL: "http://test.com/a.jpeg?w=1"}).Once()
+
+			f := &filter.Filter{Rules: filter.Rules{Extensions: []string{"jpg", "PNG"}}}
+			h := f.Wrap(ch)
+
+			process(h, "http://test.com/a.jpeg?w=1")
+			process(h, "http://test.com/b.gif")
+
+			ch.AssertExpectations(t)
+		}
+
+		t.Log("When content from comments is excluded.")
+		{
+			ch := &contentHandlerMock{}
+			ch.On("Process", handler.Source{URL: "post"}).Once()
+
+			f := &filter.Filter{Rules: filter.Rules{ExcludeComments: true}}
+			h := f.Wrap(ch)
+
+			for _, s := range []handler.Source{{URL: "post"}, {URL: "comment", Comment: true}} {
+				p := make(chan int, 1)
+				h.Process(s, p, make(chan error, 1))
+				<-p
+			}
+
+			ch.AssertExpectations(t)
+		}
+
+		t.Log("When size and MIME type are limited.")
+		{
+			p := &proberMock{}
+			p.On("Probe", "small").Return(reactor_crw.ContentInfo{Type: "image/png", Length: 10}, nil)
+			p.On("Probe", "big").Return(reactor_crw.ContentInfo{Type: "image/png", Length: 1000}, nil)
+			p.On("Probe", "video").Return(reactor_crw.ContentInfo{Type: "video/mp4", Length: 100}, nil)
+			p.On("Probe", "ok").Return(reactor_crw.ContentInfo{Type: "image/jpeg; charset=binary", Length: 100}, nil)
+			p.On("Probe", "unknown").Return(reactor_crw.ContentInfo{Length: -1}, nil)
+			p.On("Probe", "failed").Return(reactor_crw.ContentInfo{}, errors.New("error"))
+			p.On("Probe", "unsupported").Return(reactor_crw.ContentInfo{}, reactor_crw.ErrProbeUnsupported)
+
+			ch := &contentHandlerMock{}
+			ch.On("Process", handler.Source{URL: "ok"}).Once()
+			ch.On("Process", handler.Source{URL: "unknown"}).Once()
+			ch.On("Process", handler.Source{URL: "unsupported"}).Once()
+
+			f := &filter.Filter{
+				Rules:  filter.Rules{MinSize: 50, MaxSize: 500, MIMETypes: []string{"image/*"}},
+				Prober: p,
+			}
+			h := f.Wrap(ch)
+
+			for _, url := range []string{"small", "big", "video", "ok", "unknown", "failed", "unsupported"} {
+				process(h, url)
+			}
+
+			ch.AssertExpectations(t)
+		}
+	}
+}
+
+func TestFilter_Inspect(t *testing.T) {
+	f := &filter.Filter{Rules: filter.Rules{MIMETypes: []string{"image/*"}}}
+	png := "\x89PNG\r\n\x1a\n" + strings.Repeat("\x00", 16)
+
+	t.Log("Given the need to check the type of downloaded content.")
+	{
+		t.Log("When the content type is allowed.")
+		{
+			r, reason := f.Inspect(handler.Source{URL: "a.png"}, strings.NewReader(png))
+			require.Empty(t, reason)
+
+			data, err := ioutil.ReadAll(r)
+			require.NoError(t, err)
+			require.Equal(t, png, string(data), "Expected the whole content to be returned")
+		}
+
+		t.Log("When the content type isn't allowed.")
+		{
+			_, reason := f.Inspect(handler.Source{URL: "a.png"}, strings.NewReader("<html><body>not found</body></html>"))
+			require.Equal(t, `type "text/html" isn't allowed`, reason)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	t.Log("Given the need to parse human readable sizes.")
+	{
+		sizes := map[string]int64{
+			"512":   512,
+			"300K":  300 << 10,
+			"10MB":  10 << 20,
+			"1.5g":  3 << 29,
+			"100 b": 100,
+		}
+
+		for s, expected := range sizes {
+			n, err := filter.ParseSize(s)
+			require.NoError(t, err, "Wasn't expected an error for %s", s)
+			require.Equal(t, expected, n, s)
+		}
+
+		_, err := filter.ParseSize("big")
+		require.Error(t, err, "Expected an error for invalid size")
+	}
+}
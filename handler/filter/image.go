@@ -0,0 +1,137 @@
+package filter
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	// Register decoders of supported image formats.
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	_ "golang.org/x/image/webp"
+
+	"reactor-crw/handler"
+	"reactor-crw/logging"
+)
+
+// aspectTolerance is the allowed relative difference between the aspect ratio
+// of an image and the required one. It allows images like 1366x768 to match
+// 16:9.
+const aspectTolerance = 0.01
+
+// ImageRules describe which images should be saved. Zero values disable the
+// corresponding checks.
+type ImageRules struct {
+	// MinWidth and MinHeight limit the image dimensions in pixels.
+	MinWidth  int
+	MinHeight int
+
+	// Aspect is the required ratio of the image width to its height.
+	Aspect float64
+}
+
+// Empty reports whether there are no rules to check.
+func (r ImageRules) Empty() bool {
+	return r.MinWidth <= 0 && r.MinHeight <= 0 && r.Aspect <= 0
+}
+
+// check returns the reason the image doesn't match the rules or an empty
+// string if it does.
+func (r ImageRules) check(c image.Config) string {
+	if r.MinWidth > 0 && c.Width < r.MinWidth {
+		return fmt.Sprintf("width %d is less than %d", c.Width, r.MinWidth)
+	}
+	if r.MinHeight > 0 && c.Height < r.MinHeight {
+		return fmt.Sprintf("height %d is less than %d", c.Height, r.MinHeight)
+	}
+
+	if r.Aspect > 0 {
+		if c.Height == 0 {
+			return "aspect ratio is unknown"
+		}
+		aspect := float64(c.Width) / float64(c.Height)
+		if math.Abs(aspect-r.Aspect)/r.Aspect > aspectTolerance {
+			return fmt.Sprintf("aspect ratio %.2f isn't %.2f", aspect, r.Aspect)
+		}
+	}
+
+	return ""
+}
+
+// ImageFilter checks dimensions of images while they're downloaded. Only the
+// image header is decoded, so images are checked before they're written.
+// JPEG, PNG, GIF and WebP images are supported.
+type ImageFilter struct {
+	Rules ImageRules
+
+	// Logger receives a record for each content that cannot be decoded. It's
+	// optional.
+	Logger logging.Logger
+}
+
+// Inspect decodes the image header from r and returns the reason the image
+// doesn't match the rules or an empty string if it does. Content that isn't
+// a supported image always matches. The returned reader yields the whole
+// content including the decoded header.
+func (f *ImageFilter) Inspect(s handler.Source, r io.Reader) (io.Reader, string) {
+	header := &bytes.Buffer{}
+	c, _, err := image.DecodeConfig(io.TeeReader(r, header))
+	content := io.MultiReader(header, r)
+
+	if err != nil {
+		if err != image.ErrFormat {
+			f.log().Warn("cannot decode image header, dimensions aren't checked", "url", s.URL, "error", err)
+		}
+		return content, ""
+	}
+
+	return content, f.Rules.check(c)
+}
+
+func (f *ImageFilter) log() logging.Logger {
+	return logging.OrDiscard(f.Logger)
+}
+
+// ParseAspect converts an aspect ratio like 16:9 or 1.78 to a number.
+func ParseAspect(s string) (float64, error) {
+	v := strings.TrimSpace(s)
+
+	var (
+		a   float64
+		err error
+	)
+	if w, h, ok := cut(v, ":"); ok {
+		var wf, hf float64
+		wf, err = strconv.ParseFloat(w, 64)
+		if err == nil {
+			hf, err = strconv.ParseFloat(h, 64)
+		}
+		if err == nil && hf > 0 {
+			a = wf / hf
+		}
+	} else {
+		a, err = strconv.ParseFloat(v, 64)
+	}
+
+	if err != nil || a <= 0 {
+		return 0, fmt.Errorf("invalid aspect ratio: %s", s)
+	}
+
+	return a, nil
+}
+
+// cut slices s around the first instance of sep. It's strings.Cut which isn't
+// available in Go 1.16.
+func cut(s, sep string) (before, after string, found bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+
+	return s, "", false
+}
@@ -0,0 +1,102 @@
+//go:build unit
+// +build unit
+
+package filter_test
+
+import (
+	"bytes"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"reactor-crw/handler"
+	"reactor-crw/handler/filter"
+)
+
+func encodeImage(t *testing.T, format string, w, h int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	buf := &bytes.Buffer{}
+
+	var err error
+	switch format {
+	case "png":
+		err = png.Encode(buf, img)
+	case "jpeg":
+		err = jpeg.Encode(buf, img, nil)
+	case "gif":
+		err = gif.Encode(buf, img, nil)
+	}
+	require.NoError(t, err, "Wasn't expected an error during encoding")
+
+	return buf.Bytes()
+}
+
+func TestImageFilter_Inspect(t *testing.T) {
+	f := &filter.ImageFilter{Rules: filter.ImageRules{MinWidth: 160, Aspect: 16.0 / 9}}
+	s := handler.Source{URL: "http://test.com/pics/picture"}
+
+	t.Log("Given the need to check image dimensions.")
+	{
+		t.Log("When an image matches the rules.")
+		{
+			for _, format := range []string{"png", "jpeg", "gif"} {
+				data := encodeImage(t, format, 320, 180)
+
+				r, reason := f.Inspect(s, bytes.NewReader(data))
+				require.Empty(t, reason, "Expected %s image to match", format)
+
+				content, _ := ioutil.ReadAll(r)
+				require.Equal(t, data, content, "Expected the whole %s image to be read", format)
+			}
+		}
+
+		t.Log("When an image is too small.")
+		{
+			_, reason := f.Inspect(s, bytes.NewReader(encodeImage(t, "png", 96, 54)))
+			require.Contains(t, reason, "width")
+		}
+
+		t.Log("When an image has another aspect ratio.")
+		{
+			_, reason := f.Inspect(s, bytes.NewReader(encodeImage(t, "jpeg", 400, 300)))
+			require.Contains(t, reason, "aspect ratio")
+		}
+
+		t.Log("When content isn't an image.")
+		{
+			r, reason := f.Inspect(s, strings.NewReader("not an image"))
+			require.Empty(t, reason, "Expected content that isn't an image to pass")
+
+			content, _ := ioutil.ReadAll(r)
+			require.Equal(t, "not an image", string(content))
+		}
+	}
+}
+
+func TestParseAspect(t *testing.T) {
+	t.Log("Given the need to parse aspect ratios.")
+	{
+		aspects := map[string]float64{
+			"16:9": 16.0 / 9,
+			"4:3":  4.0 / 3,
+			"1.5":  1.5,
+		}
+
+		for s, expected := range aspects {
+			a, err := filter.ParseAspect(s)
+			require.NoError(t, err, "Wasn't expected an error for %s", s)
+			require.InDelta(t, expected, a, 1e-9, s)
+		}
+
+		for _, s := range []string{"wide", "16:0", "-1"} {
+			_, err := filter.ParseAspect(s)
+			require.Error(t, err, "Expected an error for %s", s)
+		}
+	}
+}
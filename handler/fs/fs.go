@@ -1,9 +1,11 @@
 package fs
 
 import (
+	"fmt"
 	"io"
-	"path"
 	"reactor-crw"
+	"reactor-crw/handler"
+	"reactor-crw/logging"
 )
 
 // PathResolver defines a simple interface to resolve path for content
@@ -16,8 +18,9 @@ type pathResolver interface {
 
 	// CreateFile creates a new file for downloaded content in the directory
 	// created by the CreateFolder function and returns the corresponding
-	// io.WriteCloser. If a file with such a name already exists then it'll
-	// be overwritten.
+	// io.WriteCloser. The name may contain a subfolder which will be created
+	// if needed. If a file with such a name already exists then it'll be
+	// overwritten.
 	CreateFile(name string) (io.WriteCloser, error)
 
 	// Remove removes a file or folder by its name. If provided path does not
@@ -25,10 +28,59 @@ type pathResolver interface {
 	Remove(name string)
 }
 
+// Inspector checks content while it's downloaded, before it's written.
+type Inspector interface {
+	// Inspect reads the beginning of the content from r and returns the
+	// reason the content shouldn't be saved or an empty string if it should.
+	// The returned reader yields the whole content including the part read
+	// by the inspector.
+	Inspect(s handler.Source, r io.Reader) (io.Reader, string)
+}
+
+// Inspectors combines inspectors, so the content is saved only if all of them
+// accept it.
+type Inspectors []Inspector
+
+// Inspect implements Inspector. Inspectors are called in order until one of
+// them rejects the content.
+func (is Inspectors) Inspect(s handler.Source, r io.Reader) (io.Reader, string) {
+	for _, i := range is {
+		var reason string
+		if r, reason = i.Inspect(s, r); reason != "" {
+			return r, reason
+		}
+	}
+
+	return r, ""
+}
+
 // FileSaver defines ContentHandler implementation that will download content
 // and save it to the host's file system. It resolves the corresponding file
-// path with PathResolver.
+// path with PathResolver and names files with NameResolver.
 type FileSaver struct {
+	// NameResolver resolves names of saved files.
+	NameResolver
+
+	// Logger receives a record for each saved or removed file. It's optional.
+	Logger logging.Logger
+
+	// Inspector skips content that shouldn't be saved. It's optional.
+	Inspector Inspector
+
+	// Limiter caps the bandwidth shared by all downloads using it. It's
+	// optional.
+	Limiter *Limiter
+
+	// DownloadRate caps the bandwidth of each download in bytes per second.
+	// Zero means no limit.
+	DownloadRate int64
+
+	// MinSize and MaxSize limit the size of saved files in bytes. Files out
+	// of the limits are removed. The download stops as soon as MaxSize is
+	// exceeded. Zero values mean no limit.
+	MinSize int64
+	MaxSize int64
+
 	pr pathResolver
 	t  reactor_crw.Transport
 }
@@ -51,12 +103,18 @@ func NewFileSaver(pr pathResolver, t reactor_crw.Transport, baseFolder string) (
 // Process downloads content by the corresponding URL by making an HTTP request
 // and saves the result to the file system. In case of error during saving the
 // content the corresponding file will be deleted from the file system.
-func (f *FileSaver) Process(url string, progress chan<- int, e chan<- error) {
+func (f *FileSaver) Process(s handler.Source, progress chan<- int, e chan<- error) {
 	defer func() {
 		progress <- 1
 	}()
 
-	data, err := f.t.FetchData(url)
+	name, err := f.Resolve(s)
+	if err != nil {
+		e <- err
+		return
+	}
+
+	data, err := f.t.FetchData(s.URL)
 	if err != nil {
 		e <- err
 		return
@@ -66,7 +124,16 @@ func (f *FileSaver) Process(url string, progress chan<- int, e chan<- error) {
 		_ = b.Close()
 	}(data)
 
-	file, err := f.pr.CreateFile(path.Base(url))
+	var content io.Reader = data
+	if f.Inspector != nil {
+		var reason string
+		if content, reason = f.Inspector.Inspect(s, data); reason != "" {
+			f.log().Debug("source skipped", "url", s.URL, "reason", reason)
+			return
+		}
+	}
+
+	file, err := f.pr.CreateFile(name)
 	if err != nil {
 		e <- err
 		return
@@ -76,9 +143,53 @@ func (f *FileSaver) Process(url string, progress chan<- int, e chan<- error) {
 		_ = f.Close()
 	}(file)
 
-	_, err = io.Copy(file, data)
+	content = f.limit(content)
+	if f.MaxSize > 0 {
+		content = io.LimitReader(content, f.MaxSize+1)
+	}
+
+	n, err := io.Copy(file, content)
 	if err != nil {
-		f.pr.Remove(path.Base(url))
+		f.pr.Remove(name)
+		f.log().Warn("incomplete file removed", "file", name, "error", err)
 		e <- err
+		return
+	}
+
+	if reason := f.checkSize(n); reason != "" {
+		f.pr.Remove(name)
+		f.log().Debug("source skipped", "url", s.URL, "reason", reason)
+		return
+	}
+
+	f.log().Debug("file saved", "url", s.URL, "file", name, "bytes", n)
+}
+
+// checkSize returns the reason the file of the size shouldn't be kept or an
+// empty string if it should.
+func (f *FileSaver) checkSize(n int64) string {
+	if f.MaxSize > 0 && n > f.MaxSize {
+		return fmt.Sprintf("size is greater than %d", f.MaxSize)
 	}
+	if f.MinSize > 0 && n < f.MinSize {
+		return fmt.Sprintf("size %d is less than %d", n, f.MinSize)
+	}
+
+	return ""
+}
+
+// limit wraps the content, so it's read within the bandwidth caps.
+func (f *FileSaver) limit(r io.Reader) io.Reader {
+	if f.DownloadRate > 0 {
+		r = NewLimiter(f.DownloadRate).Reader(r)
+	}
+	if f.Limiter != nil {
+		r = f.Limiter.Reader(r)
+	}
+
+	return r
+}
+
+func (f *FileSaver) log() logging.Logger {
+	return logging.OrDiscard(f.Logger)
 }
@@ -8,8 +8,11 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"reactor-crw/handler"
 	"reactor-crw/handler/fs"
+	"strings"
 	"testing"
+	"text/template"
 
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -42,6 +45,14 @@ func (m *transportMock) FetchData(url string) (io.ReadCloser, error) {
 	return args.Get(0).(io.ReadCloser), args.Error(1)
 }
 
+type inspectorMock struct {
+	reason string
+}
+
+func (m *inspectorMock) Inspect(_ handler.Source, r io.Reader) (io.Reader, string) {
+	return r, m.reason
+}
+
 func TestNewFileSaver(t *testing.T) {
 	pr := pathResolverMock{}
 	trp := transportMock{}
@@ -85,7 +96,7 @@ func TestFileSaver_Process(t *testing.T) {
 		{
 			trp.On("FetchData", "file-title.txt").Return(tmlFile, errors.New("error")).Once()
 
-			fileSaver.Process("file-title.txt", p, e)
+			fileSaver.Process(handler.Source{URL: "file-title.txt"}, p, e)
 			require.Error(t, <-e, "Expected an error during create file")
 			<-p
 		}
@@ -95,7 +106,7 @@ func TestFileSaver_Process(t *testing.T) {
 			trp.On("FetchData", "file-title.txt").Return(tmlFile, nil).Once()
 			pr.On("CreateFile", "file-title.txt").Return(tmlFile, errors.New("error")).Once()
 
-			fileSaver.Process("file-title.txt", p, e)
+			fileSaver.Process(handler.Source{URL: "file-title.txt"}, p, e)
 			require.Error(t, <-e, "Expected an error during create file")
 			<-p
 		}
@@ -106,11 +117,102 @@ func TestFileSaver_Process(t *testing.T) {
 			pr.On("CreateFile", "file-title.txt").Return(tmlFile, nil).Once()
 			pr.On("Remove", mock.Anything)
 
-			fileSaver.Process("file-title.txt", p, e)
+			fileSaver.Process(handler.Source{URL: "file-title.txt"}, p, e)
 			require.Error(t, <-e, "Expected an error file copying")
 			<-p
 		}
 
+		t.Log("When content was found in comments.")
+		{
+			tmlFile, _ = ioutil.TempFile(os.TempDir(), "comment-file-title.txt")
+			fileSaver.CommentsFolder = "comments"
+
+			trp.On("FetchData", "comment-file-title.txt").Return(tmlFile, nil).Once()
+			pr.On("CreateFile", "comments/comment-file-title.txt").Return(tmlFile, nil).Once()
+
+			fileSaver.Process(handler.Source{URL: "comment-file-title.txt", Comment: true}, p, e)
+			<-p
+			pr.AssertCalled(t, "CreateFile", "comments/comment-file-title.txt")
+		}
+
+		t.Log("When file name template is provided.")
+		{
+			tmlFile, _ = ioutil.TempFile(os.TempDir(), "template-file-title.txt")
+			fileSaver.NameTemplate = template.Must(template.New("name").Parse("prefix-{{.Name}}{{.Ext}}"))
+
+			trp.On("FetchData", "http://test.com/pics/picture.jpeg").Return(tmlFile, nil).Once()
+			pr.On("CreateFile", "prefix-picture.jpeg").Return(tmlFile, nil).Once()
+
+			fileSaver.Process(handler.Source{URL: "http://test.com/pics/picture.jpeg"}, p, e)
+			<-p
+			pr.AssertCalled(t, "CreateFile", "prefix-picture.jpeg")
+			fileSaver.NameTemplate = nil
+		}
+
+		t.Log("When file name template cannot be executed.")
+		{
+			fileSaver.NameTemplate = template.Must(template.New("name").Parse("{{.Unknown}}"))
+
+			fileSaver.Process(handler.Source{URL: "http://test.com/pics/picture.jpeg"}, p, e)
+			require.Error(t, <-e, "Expected an error during resolving file name")
+			<-p
+			fileSaver.NameTemplate = nil
+		}
+
+		t.Log("When file name template produces unsafe names.")
+		{
+			tmlFile, _ = ioutil.TempFile(os.TempDir(), "unsafe-file-title.txt")
+			fileSaver.NameTemplate = template.Must(template.New("name").Parse("a:b//./{{.Base}}"))
+
+			trp.On("FetchData", "http://test.com/pics/unsafe.jpeg").Return(tmlFile, nil).Once()
+			pr.On("CreateFile", "a_b/unsafe.jpeg").Return(tmlFile, nil).Once()
+
+			fileSaver.Process(handler.Source{URL: "http://test.com/pics/unsafe.jpeg"}, p, e)
+			<-p
+			pr.AssertCalled(t, "CreateFile", "a_b/unsafe.jpeg")
+
+			fileSaver.NameTemplate = template.Must(template.New("name").Parse("../{{.Base}}"))
+
+			fileSaver.Process(handler.Source{URL: "http://test.com/pics/unsafe.jpeg"}, p, e)
+			require.Error(t, <-e, "Expected an error for a name outside the folder")
+			<-p
+			fileSaver.NameTemplate = nil
+		}
+
+		t.Log("When inspector rejects the content.")
+		{
+			tmlFile, _ = ioutil.TempFile(os.TempDir(), "skipped-file-title.txt")
+			fileSaver.Inspector = &inspectorMock{reason: "too small"}
+
+			trp.On("FetchData", "skipped-file-title.txt").Return(tmlFile, nil).Once()
+
+			fileSaver.Process(handler.Source{URL: "skipped-file-title.txt"}, p, e)
+			<-p
+			pr.AssertNotCalled(t, "CreateFile", "skipped-file-title.txt")
+			fileSaver.Inspector = nil
+		}
+
+		t.Log("When the content is out of the size limits.")
+		{
+			fileSaver.MinSize, fileSaver.MaxSize = 4, 8
+
+			for name, body := range map[string]string{"big.txt": "0123456789", "small.txt": "012"} {
+				out, _ := ioutil.TempFile(os.TempDir(), name)
+
+				trp.On("FetchData", name).Return(ioutil.NopCloser(strings.NewReader(body)), nil).Once()
+				pr.On("CreateFile", name).Return(out, nil).Once()
+
+				fileSaver.Process(handler.Source{URL: name}, p, e)
+				<-p
+				require.Empty(t, e, "Wasn't expected an error for skipped content")
+				pr.AssertCalled(t, "Remove", name)
+
+				data, _ := ioutil.ReadFile(out.Name())
+				require.LessOrEqual(t, len(data), 9, "Expected the download to stop after the maximum size")
+			}
+			fileSaver.MinSize, fileSaver.MaxSize = 0, 0
+		}
+
 		t.Log("When all data correct.")
 		{
 			tmlFile, _ = ioutil.TempFile(os.TempDir(), "new-file-title.txt")
@@ -118,7 +220,7 @@ func TestFileSaver_Process(t *testing.T) {
 			trp.On("FetchData", "new-file-title.txt").Return(tmlFile, nil).Once()
 			pr.On("CreateFile", "new-file-title.txt").Return(tmlFile, nil).Once()
 
-			fileSaver.Process("new-file-title.txt", p, e)
+			fileSaver.Process(handler.Source{URL: "new-file-title.txt"}, p, e)
 			select {
 			case err := <-e:
 				require.NoError(t, err, "Wasn't expected an error on new file process")
@@ -0,0 +1,71 @@
+package fs
+
+import (
+	"io"
+	"time"
+
+	"reactor-crw/pace"
+)
+
+const (
+	// maxChunk limits the amount of bytes read at once, so waits for the
+	// bandwidth are short and downloads sharing it progress evenly.
+	maxChunk = 32 << 10
+
+	// minChunk keeps reads efficient for low rates.
+	minChunk = 512
+)
+
+// Limiter limits the bandwidth of readers wrapped by it. It's safe for
+// concurrent use, so the same instance can be shared by all workers to cap the
+// total bandwidth.
+type Limiter struct {
+	rate  float64
+	chunk int
+	pacer pace.Pacer
+}
+
+// NewLimiter creates a new *Limiter allowing to read at most bps bytes per
+// second.
+func NewLimiter(bps int64) *Limiter {
+	chunk := int(bps / 10)
+	if chunk > maxChunk {
+		chunk = maxChunk
+	}
+	if chunk < minChunk {
+		chunk = minChunk
+	}
+
+	return &Limiter{
+		rate:  float64(bps),
+		chunk: chunk,
+	}
+}
+
+// Reader wraps the reader, so reading from it is limited by the limiter.
+func (l *Limiter) Reader(r io.Reader) io.Reader {
+	return &limitedReader{r: r, l: l}
+}
+
+// wait reserves the bandwidth for n bytes and blocks until it's available.
+func (l *Limiter) wait(n int) {
+	l.pacer.Wait(time.Duration(float64(n) / l.rate * float64(time.Second)))
+}
+
+type limitedReader struct {
+	r io.Reader
+	l *Limiter
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if len(p) > r.l.chunk {
+		p = p[:r.l.chunk]
+	}
+
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.l.wait(n)
+	}
+
+	return n, err
+}
@@ -0,0 +1,61 @@
+//go:build unit
+// +build unit
+
+package fs_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reactor-crw/handler/fs"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter_Reader(t *testing.T) {
+	t.Log("Given the need to limit the bandwidth of downloads.")
+	{
+		t.Log("When a single reader is limited.")
+		{
+			l := fs.NewLimiter(10 << 10)
+			data := bytes.Repeat([]byte("a"), 3<<10)
+
+			start := time.Now()
+			res, err := ioutil.ReadAll(l.Reader(bytes.NewReader(data)))
+			require.NoError(t, err)
+			require.Equal(t, data, res)
+
+			// The first chunk is read right away, the rest takes at least 0.2s.
+			require.GreaterOrEqual(t, int64(time.Since(start)), int64(200*time.Millisecond))
+		}
+
+		t.Log("When the limiter is shared by multiple readers.")
+		{
+			l := fs.NewLimiter(20 << 10)
+
+			start := time.Now()
+
+			var wg sync.WaitGroup
+			errs := make(chan error, 3)
+			for i := 0; i < cap(errs); i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, err := ioutil.ReadAll(l.Reader(bytes.NewReader(make([]byte, 4<<10))))
+					errs <- err
+				}()
+			}
+			wg.Wait()
+			close(errs)
+
+			for err := range errs {
+				require.NoError(t, err)
+			}
+
+			// 12K are read with the rate of 20K per second.
+			require.GreaterOrEqual(t, int64(time.Since(start)), int64(400*time.Millisecond))
+		}
+	}
+}
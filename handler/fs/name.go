@@ -0,0 +1,111 @@
+package fs
+
+import (
+	"fmt"
+	"path"
+	"reactor-crw/handler"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// NameResolver resolves file names for content sources. It's used by
+// FileSaver and by handlers that leave downloading to other tools, so files
+// are named the same way.
+type NameResolver struct {
+	// CommentsFolder contains a name of the subfolder for content found in
+	// post comments. If it's empty such content is saved along with the rest.
+	CommentsFolder string
+
+	// NameTemplate allows customizing names of saved files. The template is
+	// executed against NameData. If it's nil the last element of the content
+	// URL is used as is.
+	NameTemplate *template.Template
+}
+
+// NameData contains values available within NameResolver.NameTemplate.
+type NameData struct {
+	// URL is a full content URL.
+	URL string
+
+	// Base is the last element of the URL path. Example: picture-123.jpeg.
+	Base string
+
+	// Name is Base without the extension. Example: picture-123.
+	Name string
+
+	// Ext is the extension of Base including the dot. Example: .jpeg.
+	Ext string
+
+	// Comment marks content found in post comments.
+	Comment bool
+}
+
+// Resolve returns the file name for the content source relative to the base
+// folder. Content found in comments is placed to NameResolver.CommentsFolder.
+// Unsafe characters of the name are replaced and names pointing outside the
+// base folder are rejected.
+func (r NameResolver) Resolve(s handler.Source) (string, error) {
+	name, err := r.fileName(s)
+	if err != nil {
+		return "", err
+	}
+
+	if s.Comment && r.CommentsFolder != "" {
+		name = path.Join(r.CommentsFolder, name)
+	}
+
+	clean, ok := cleanName(name)
+	if !ok {
+		return "", fmt.Errorf("cannot resolve file name for %s: invalid name %q", s.URL, name)
+	}
+
+	return clean, nil
+}
+
+func (r NameResolver) fileName(s handler.Source) (string, error) {
+	base := path.Base(s.URL)
+	if r.NameTemplate == nil {
+		return base, nil
+	}
+
+	ext := path.Ext(base)
+	data := NameData{
+		URL:     s.URL,
+		Base:    base,
+		Name:    strings.TrimSuffix(base, ext),
+		Ext:     ext,
+		Comment: s.Comment,
+	}
+
+	b := strings.Builder{}
+	err := r.NameTemplate.Execute(&b, data)
+	if err != nil {
+		return "", fmt.Errorf("cannot resolve file name for %s: %w", s.URL, err)
+	}
+
+	return b.String(), nil
+}
+
+// unsafeNameChars matches characters that cannot be used in file names on
+// common file systems.
+var unsafeNameChars = regexp.MustCompile(`[\\<>:"|?*\x00-\x1f]`)
+
+// cleanName makes the file name produced by a template safe to use within the
+// base folder. Unsafe characters are replaced and empty elements are dropped.
+// It reports false if the name is empty or points outside the base folder.
+func cleanName(name string) (string, bool) {
+	var elems []string
+	for _, e := range strings.Split(name, "/") {
+		e = unsafeNameChars.ReplaceAllString(strings.TrimSpace(e), "_")
+		switch e {
+		case "", ".":
+			continue
+		case "..":
+			return "", false
+		}
+		elems = append(elems, e)
+	}
+
+	return strings.Join(elems, "/"), len(elems) > 0
+}
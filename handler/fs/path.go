@@ -70,7 +70,8 @@ func (p *PathResolver) CreateFolder(name string) error {
 }
 
 // CreateFile creates a new file with the corresponding name and returns its
-// io.WriteCloser. If FSResolver.currentDest wasn't created before, then an error
+// io.WriteCloser. If the name contains a subfolder it'll be created within the
+// current dir. If FSResolver.currentDest wasn't created before, then an error
 // will be returned.
 func (p *PathResolver) CreateFile(name string) (io.WriteCloser, error) {
 	if p.currentDest == "" {
@@ -79,6 +80,11 @@ func (p *PathResolver) CreateFile(name string) (io.WriteCloser, error) {
 
 	filePath := path.Join(p.currentDest, name)
 
+	err := os.MkdirAll(path.Dir(filePath), fs.ModePerm)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create folder for file %s: %w", filePath, err)
+	}
+
 	f, err := os.Create(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create file %s: %w", filePath, err)
@@ -79,4 +79,14 @@ func TestPathResolver_CreateFile(t *testing.T) {
 	require.NoError(t, err, "File %s wasn't created", filePath)
 
 	p.Remove(filePath)
+
+	_, err = p.CreateFile("sub/filename")
+	require.NoError(t, err, "Wasn't expected an error during creating sub/filename")
+
+	filePath = "./test/sub/filename"
+	_, err = os.Stat(filePath)
+	require.NoError(t, err, "File %s wasn't created", filePath)
+
+	p.Remove(filePath)
+	p.Remove("./test/sub")
 }
@@ -0,0 +1,252 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Logger is a structured logger with levels. Args are alternating keys and
+// values added to the record. The method set matches *slog.Logger, so it can
+// be used as a Logger as well.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// Level describes the severity of a log record. Values match slog levels.
+type Level int
+
+const (
+	LevelDebug Level = -4
+	LevelInfo  Level = 0
+	LevelWarn  Level = 4
+	LevelError Level = 8
+)
+
+// String returns the level name as it's written to the log.
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	default:
+		return "LEVEL(" + strconv.Itoa(int(l)) + ")"
+	}
+}
+
+// ParseLevel converts a level name to Level. Names are case-insensitive.
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	default:
+		return 0, fmt.Errorf("unknown log level: %s", s)
+	}
+}
+
+// Format describes how records are written.
+type Format string
+
+const (
+	// FormatText writes records as space separated key=value pairs.
+	FormatText Format = "text"
+
+	// FormatJSON writes records as JSON objects, one per line.
+	FormatJSON Format = "json"
+)
+
+// ParseFormat converts a format name to Format.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case FormatText, FormatJSON:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown log format: %s", s)
+	}
+}
+
+// Discard is a Logger that drops all records.
+var Discard Logger = discard{}
+
+type discard struct{}
+
+func (discard) Debug(string, ...interface{}) {}
+func (discard) Info(string, ...interface{})  {}
+func (discard) Warn(string, ...interface{})  {}
+func (discard) Error(string, ...interface{}) {}
+
+// OrDiscard returns l or Discard if l is nil. It allows components to keep
+// their logger optional.
+func OrDiscard(l Logger) Logger {
+	if l == nil {
+		return Discard
+	}
+
+	return l
+}
+
+// badKey is used as a key for a value without a key, the same way slog does.
+const badKey = "!BADKEY"
+
+// writer is the Logger implementation returned by New.
+type writer struct {
+	mu     sync.Mutex
+	w      io.Writer
+	format Format
+	level  Level
+}
+
+// New creates a Logger that writes records of the level and above to w in the
+// format. It's safe for concurrent use.
+func New(w io.Writer, f Format, level Level) Logger {
+	return &writer{
+		w:      w,
+		format: f,
+		level:  level,
+	}
+}
+
+func (w *writer) Debug(msg string, args ...interface{}) {
+	w.log(LevelDebug, msg, args)
+}
+
+func (w *writer) Info(msg string, args ...interface{}) {
+	w.log(LevelInfo, msg, args)
+}
+
+func (w *writer) Warn(msg string, args ...interface{}) {
+	w.log(LevelWarn, msg, args)
+}
+
+func (w *writer) Error(msg string, args ...interface{}) {
+	w.log(LevelError, msg, args)
+}
+
+func (w *writer) log(level Level, msg string, args []interface{}) {
+	if level < w.level {
+		return
+	}
+
+	attrs := append([]interface{}{
+		"time", time.Now(),
+		"level", level,
+		"msg", msg,
+	}, args...)
+
+	buf := &bytes.Buffer{}
+	if w.format == FormatJSON {
+		writeJSON(buf, attrs)
+	} else {
+		writeText(buf, attrs)
+	}
+	buf.WriteByte('\n')
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	_, _ = w.w.Write(buf.Bytes())
+}
+
+// pairs calls f for each key and value of args. A value without a key gets
+// badKey.
+func pairs(args []interface{}, f func(k string, v interface{})) {
+	for len(args) > 0 {
+		k, ok := args[0].(string)
+		if !ok || len(args) == 1 {
+			f(badKey, args[0])
+			args = args[1:]
+			continue
+		}
+
+		f(k, args[1])
+		args = args[2:]
+	}
+}
+
+func writeText(buf *bytes.Buffer, args []interface{}) {
+	first := true
+	pairs(args, func(k string, v interface{}) {
+		if !first {
+			buf.WriteByte(' ')
+		}
+		first = false
+
+		buf.WriteString(k)
+		buf.WriteByte('=')
+
+		s := textValue(v)
+		if s == "" || strings.ContainsAny(s, " =\"\t\n") {
+			s = strconv.Quote(s)
+		}
+		buf.WriteString(s)
+	})
+}
+
+func writeJSON(buf *bytes.Buffer, args []interface{}) {
+	buf.WriteByte('{')
+
+	first := true
+	pairs(args, func(k string, v interface{}) {
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+
+		key, _ := json.Marshal(k)
+		buf.Write(key)
+		buf.WriteByte(':')
+
+		val, err := json.Marshal(jsonValue(v))
+		if err != nil {
+			val, _ = json.Marshal(fmt.Sprint(v))
+		}
+		buf.Write(val)
+	})
+
+	buf.WriteByte('}')
+}
+
+func textValue(v interface{}) string {
+	switch v := v.(type) {
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case time.Time:
+		return v
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return v
+	}
+}
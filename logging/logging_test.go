@@ -0,0 +1,85 @@
+//go:build unit
+// +build unit
+
+package logging_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"reactor-crw/logging"
+)
+
+func TestNew(t *testing.T) {
+	t.Log("Given the need to write structured log records.")
+	{
+		t.Log("When records are written as text.")
+		{
+			buf := &bytes.Buffer{}
+			l := logging.New(buf, logging.FormatText, logging.LevelInfo)
+
+			l.Debug("skipped")
+			l.Info("page crawled", "page", "http://test.com/tag/some tag", "found", 2, "duration", time.Second)
+
+			line := strings.TrimSpace(buf.String())
+			require.NotContains(t, line, "skipped", "Records below the level should be skipped")
+			require.Contains(t, line, ` level=INFO msg="page crawled" page="http://test.com/tag/some tag" found=2 duration=1s`)
+			require.True(t, strings.HasPrefix(line, "time="), "Record should start with the time")
+		}
+
+		t.Log("When records are written as JSON.")
+		{
+			buf := &bytes.Buffer{}
+			l := logging.New(buf, logging.FormatJSON, logging.LevelDebug)
+
+			l.Error("source failed", "url", "http://test.com/a.jpeg", "error", errors.New("cannot save"), "orphan")
+
+			var record map[string]interface{}
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &record), "Record should be a valid JSON")
+			require.Equal(t, "ERROR", record["level"])
+			require.Equal(t, "source failed", record["msg"])
+			require.Equal(t, "http://test.com/a.jpeg", record["url"])
+			require.Equal(t, "cannot save", record["error"])
+			require.Equal(t, "orphan", record["!BADKEY"])
+			require.NotEmpty(t, record["time"])
+		}
+
+		t.Log("When the logger isn't set.")
+		{
+			require.Equal(t, logging.Discard, logging.OrDiscard(nil))
+			require.NotPanics(t, func() {
+				logging.OrDiscard(nil).Error("error")
+			})
+		}
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	t.Log("Given the need to parse a log level.")
+	{
+		levels := map[string]logging.Level{
+			"debug": logging.LevelDebug,
+			"INFO":  logging.LevelInfo,
+			"warn":  logging.LevelWarn,
+			"error": logging.LevelError,
+		}
+
+		for s, expected := range levels {
+			l, err := logging.ParseLevel(s)
+			require.NoError(t, err, "Wasn't expected an error for %s", s)
+			require.Equal(t, expected, l)
+		}
+
+		_, err := logging.ParseLevel("verbose")
+		require.Error(t, err, "Expected an error for unknown level")
+
+		_, err = logging.ParseFormat("xml")
+		require.Error(t, err, "Expected an error for unknown format")
+	}
+}
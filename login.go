@@ -0,0 +1,129 @@
+package reactor_crw
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"reactor-crw/parser"
+)
+
+var (
+	// ErrLoginFailed returned when the site rejects provided credentials.
+	ErrLoginFailed = errors.New("login failed, check the username and the password")
+
+	// ErrNoCookieJar returned when the client of the transport cannot keep
+	// the session.
+	ErrNoCookieJar = errors.New("cannot login without a cookie jar")
+
+	// ErrNoLoginForm returned when the login page has no CSRF token, e.g.
+	// the site changed its markup or the page is blocked.
+	ErrNoLoginForm = errors.New("login form not found")
+)
+
+const (
+	// htmlLoginToken matches the CSRF token of the login form.
+	htmlLoginToken = `input[name="signin[_csrf_token]"]`
+
+	// htmlLogoutLink matches the logout link shown to signed in users only.
+	htmlLogoutLink = `a[href*="logout"]`
+)
+
+// Login signs in to the site using its login form. Session cookies are kept
+// in the cookie jar of the client, so subsequent requests are made on behalf
+// of the user.
+//
+// Example: t.Login("http://joyreactor.cc", "username", "password")
+func (t *HttpTransport) Login(site, username, password string) error {
+	if t.client.Jar == nil {
+		return ErrNoCookieJar
+	}
+
+	loginURL := strings.TrimSuffix(site, "/") + "/login"
+
+	req, err := t.prepareRequest(http.MethodGet, loginURL)
+	if err != nil {
+		return err
+	}
+
+	token, err := t.findAttr(req, htmlLoginToken, "value")
+	if err != nil {
+		return err
+	}
+	if token == "" {
+		return fmt.Errorf("%w at %s", ErrNoLoginForm, loginURL)
+	}
+
+	form := url.Values{
+		"signin[username]":    {username},
+		"signin[password]":    {password},
+		"signin[remember]":    {"on"},
+		"signin[_csrf_token]": {token},
+	}
+
+	body := form.Encode()
+
+	req, err = t.prepareRequest(http.MethodPost, loginURL)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Body = io.NopCloser(strings.NewReader(body))
+	req.ContentLength = int64(len(body))
+
+	logout, err := t.findAttr(req, htmlLogoutLink, "href")
+	if err != nil {
+		return err
+	}
+	if logout == "" {
+		return ErrLoginFailed
+	}
+
+	t.log().Info("logged in", "site", site, "username", username)
+
+	return nil
+}
+
+// findAttr makes the request and returns the attribute of the first element
+// found by the query in the response. An empty string is returned if nothing
+// is found.
+func (t *HttpTransport) findAttr(req *http.Request, query, attr string) (string, error) {
+	// The request is aborted when the body stalls.
+	cancel := context.CancelFunc(func() {})
+	if t.StallTimeout > 0 {
+		req, cancel = withCancel(req)
+	}
+	defer cancel()
+
+	res, err := t.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("cannot make request to %s: %w", req.URL, err)
+	}
+
+	body := res.Body
+	if t.StallTimeout > 0 {
+		body = newStallBody(res.Body, req.URL.String(), t.StallTimeout, cancel)
+	}
+	defer func() {
+		_ = body.Close()
+	}()
+
+	if res.StatusCode >= http.StatusBadRequest {
+		return "", fmt.Errorf("cannot make request to %s: unexpected status %d", req.URL, res.StatusCode)
+	}
+
+	found := make(parser.QueryResult)
+	if err = (&parser.Html{}).FindAttrMap(body, parser.QueryAttrMap{query: attr}, found); err != nil {
+		return "", err
+	}
+
+	for v := range found {
+		return v, nil
+	}
+
+	return "", nil
+}
@@ -0,0 +1,111 @@
+//go:build unit
+// +build unit
+
+package reactor_crw_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"reactor-crw"
+)
+
+func TestHttpTransport_Login(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			http.SetCookie(w, &http.Cookie{Name: "csrf", Value: "token"})
+			_, _ = fmt.Fprint(w, `<form><input name="signin[_csrf_token]" value="token"></form>`)
+			return
+		}
+
+		c, err := r.Cookie("csrf")
+		if err != nil || c.Value != r.PostFormValue("signin[_csrf_token]") || r.PostFormValue("signin[password]") != "secret" {
+			_, _ = fmt.Fprint(w, `<form></form>`)
+			return
+		}
+
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "user"})
+		_, _ = fmt.Fprint(w, `<a href="/logout">Logout</a>`)
+	}))
+	defer srv.Close()
+
+	t.Log("Given the need to sign in.")
+	{
+		t.Log("When credentials are valid.")
+		{
+			jar, _ := cookiejar.New(nil)
+			httpTransport := reactor_crw.NewHttpTransport(&http.Client{Jar: jar}, nil)
+
+			err := httpTransport.Login(srv.URL, "user", "secret")
+			require.NoError(t, err, "Wasn't expected an error during login")
+
+			req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+			require.Contains(t, jar.Cookies(req.URL), &http.Cookie{Name: "session", Value: "user"})
+		}
+
+		t.Log("When credentials are invalid.")
+		{
+			jar, _ := cookiejar.New(nil)
+			httpTransport := reactor_crw.NewHttpTransport(&http.Client{Jar: jar}, nil)
+
+			err := httpTransport.Login(srv.URL, "user", "wrong")
+			require.ErrorIs(t, err, reactor_crw.ErrLoginFailed)
+		}
+
+		t.Log("When the client has no cookie jar.")
+		{
+			httpTransport := reactor_crw.NewHttpTransport(http.DefaultClient, nil)
+
+			err := httpTransport.Login(srv.URL, "user", "secret")
+			require.ErrorIs(t, err, reactor_crw.ErrNoCookieJar)
+		}
+
+		t.Log("When the login page has no login form.")
+		{
+			empty := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodGet {
+					t.Error("Wasn't expected the form to be posted")
+				}
+				_, _ = fmt.Fprint(w, `<p>Access denied</p>`)
+			}))
+			defer empty.Close()
+
+			jar, _ := cookiejar.New(nil)
+			httpTransport := reactor_crw.NewHttpTransport(&http.Client{Jar: jar}, nil)
+
+			err := httpTransport.Login(empty.URL, "user", "secret")
+			require.ErrorIs(t, err, reactor_crw.ErrNoLoginForm)
+		}
+
+		t.Log("When the login page stalls.")
+		{
+			done := make(chan struct{})
+			stalled := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = fmt.Fprint(w, "<form>")
+				w.(http.Flusher).Flush()
+				select {
+				case <-r.Context().Done():
+				case <-done:
+				}
+			}))
+			defer stalled.Close()
+			defer close(done)
+
+			jar, _ := cookiejar.New(nil)
+			httpTransport := reactor_crw.NewHttpTransport(&http.Client{Jar: jar}, nil)
+			httpTransport.StallTimeout = 50 * time.Millisecond
+
+			err := httpTransport.Login(stalled.URL, "user", "secret")
+
+			var se *reactor_crw.StallError
+			require.True(t, errors.As(err, &se), "Expected a stall error, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "reactor_crw"
+
+// Metrics contains Prometheus metrics of the transport, the crawler and the
+// client. All methods are safe to call on a nil *Metrics, in this case nothing
+// is recorded. This way instrumented components don't need to check whether
+// metrics are enabled.
+type Metrics struct {
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	bytes           prometheus.Counter
+	pages           prometheus.Counter
+	items           prometheus.Counter
+	downloads       *prometheus.CounterVec
+	downloadErrors  *prometheus.CounterVec
+	workers         prometheus.Gauge
+	busyWorkers     prometheus.Gauge
+}
+
+// New creates a new *Metrics and registers all its collectors with the
+// registerer.
+func New(r prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Amount of HTTP requests by host and status code. Requests failed without a response have \"error\" code.",
+		}, []string{"host", "code"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Time spent waiting for HTTP response headers by host.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"host"}),
+		bytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "downloaded_bytes_total",
+			Help:      "Amount of bytes read from HTTP response bodies.",
+		}),
+		pages: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "pages_fetched_total",
+			Help:      "Amount of pages fetched and parsed by the crawler.",
+		}),
+		items: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "items_discovered_total",
+			Help:      "Amount of content sources found by the crawler.",
+		}),
+		downloads: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "downloads_total",
+			Help:      "Amount of processed content sources by content type and result.",
+		}, []string{"type", "result"}),
+		downloadErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "download_errors_total",
+			Help:      "Amount of failed content sources by content type and error class.",
+		}, []string{"type", "class"}),
+		workers: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "workers",
+			Help:      "Amount of workers started by running clients.",
+		}),
+		busyWorkers: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "workers_busy",
+			Help:      "Amount of workers processing a content source at the moment.",
+		}),
+	}
+
+	r.MustRegister(
+		m.requests,
+		m.requestDuration,
+		m.bytes,
+		m.pages,
+		m.items,
+		m.downloads,
+		m.downloadErrors,
+		m.workers,
+		m.busyWorkers,
+	)
+
+	return m
+}
+
+// ObserveRequest records the HTTP request to the host. Code is the response
+// status code or 0 if the request failed without a response.
+func (m *Metrics) ObserveRequest(host string, code int, d time.Duration) {
+	if m == nil {
+		return
+	}
+
+	c := "error"
+	if code != 0 {
+		c = strconv.Itoa(code)
+	}
+
+	m.requests.WithLabelValues(host, c).Inc()
+	m.requestDuration.WithLabelValues(host).Observe(d.Seconds())
+}
+
+// AddBytes records the amount of bytes read from a response body.
+func (m *Metrics) AddBytes(n int) {
+	if m == nil || n <= 0 {
+		return
+	}
+
+	m.bytes.Add(float64(n))
+}
+
+// PageFetched records a page fetched by the crawler.
+func (m *Metrics) PageFetched() {
+	if m == nil {
+		return
+	}
+
+	m.pages.Inc()
+}
+
+// ItemsDiscovered records the amount of content sources found by the crawler.
+func (m *Metrics) ItemsDiscovered(n int) {
+	if m == nil {
+		return
+	}
+
+	m.items.Add(float64(n))
+}
+
+// DownloadSucceeded records a content source of the type that was processed
+// without errors. The type is the one the source was found by, e.g. image or
+// mp4.
+func (m *Metrics) DownloadSucceeded(contentType string) {
+	if m == nil {
+		return
+	}
+
+	m.downloads.WithLabelValues(typeLabel(contentType), "success").Inc()
+}
+
+// DownloadFailed records a content source of the type that failed with the
+// error.
+func (m *Metrics) DownloadFailed(contentType string, err error) {
+	if m == nil {
+		return
+	}
+
+	t := typeLabel(contentType)
+	m.downloads.WithLabelValues(t, "failure").Inc()
+	m.downloadErrors.WithLabelValues(t, ErrorClass(err)).Inc()
+}
+
+// WorkersStarted adds n workers to the pool size. Negative values remove
+// workers from the pool.
+func (m *Metrics) WorkersStarted(n int) {
+	if m == nil {
+		return
+	}
+
+	m.workers.Add(float64(n))
+}
+
+// WorkerBusy marks one worker as busy. The returned function marks it as
+// idle again.
+func (m *Metrics) WorkerBusy() func() {
+	if m == nil {
+		return func() {}
+	}
+
+	m.busyWorkers.Inc()
+
+	return m.busyWorkers.Dec
+}
+
+// typeLabel returns the label of the content type. Sources of unknown type
+// are reported as "other".
+func typeLabel(contentType string) string {
+	if contentType == "" {
+		return "other"
+	}
+
+	return contentType
+}
+
+// ErrorClass returns the error class label for the error.
+func ErrorClass(err error) string {
+	var (
+		netErr  net.Error
+		pathErr *fs.PathError
+	)
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "cancelled"
+	case errors.As(err, &netErr):
+		if netErr.Timeout() {
+			return "timeout"
+		}
+		return "network"
+	case errors.As(err, &pathErr):
+		return "fs"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,125 @@
+//go:build unit
+// +build unit
+
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"reactor-crw/metrics"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestMetrics(t *testing.T) {
+	t.Log("Given the need to record crawler metrics.")
+	{
+		t.Log("When metrics are disabled.")
+		{
+			var m *metrics.Metrics
+
+			require.NotPanics(t, func() {
+				m.ObserveRequest("host", 200, time.Second)
+				m.AddBytes(1)
+				m.PageFetched()
+				m.ItemsDiscovered(1)
+				m.DownloadSucceeded("image")
+				m.DownloadFailed("image", errors.New("error"))
+				m.WorkersStarted(1)
+				m.WorkerBusy()()
+			})
+		}
+
+		t.Log("When content sources are processed.")
+		{
+			reg := prometheus.NewRegistry()
+			m := metrics.New(reg)
+
+			m.DownloadSucceeded("gif")
+			m.DownloadSucceeded("")
+			m.DownloadFailed("mp4", &fs.PathError{Op: "open", Path: "b.mp4", Err: fs.ErrPermission})
+
+			expected := `
+				# HELP reactor_crw_downloads_total Amount of processed content sources by content type and result.
+				# TYPE reactor_crw_downloads_total counter
+				reactor_crw_downloads_total{result="failure",type="mp4"} 1
+				reactor_crw_downloads_total{result="success",type="gif"} 1
+				reactor_crw_downloads_total{result="success",type="other"} 1
+				# HELP reactor_crw_download_errors_total Amount of failed content sources by content type and error class.
+				# TYPE reactor_crw_download_errors_total counter
+				reactor_crw_download_errors_total{class="fs",type="mp4"} 1
+			`
+			err := testutil.GatherAndCompare(
+				reg, strings.NewReader(expected),
+				"reactor_crw_downloads_total", "reactor_crw_download_errors_total",
+			)
+			require.NoError(t, err)
+		}
+
+		t.Log("When workers are busy.")
+		{
+			reg := prometheus.NewRegistry()
+			m := metrics.New(reg)
+
+			m.WorkersStarted(2)
+			idle := m.WorkerBusy()
+
+			expected := `
+				# HELP reactor_crw_workers_busy Amount of workers processing a content source at the moment.
+				# TYPE reactor_crw_workers_busy gauge
+				reactor_crw_workers_busy 1
+			`
+			require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected), "reactor_crw_workers_busy"))
+
+			idle()
+			m.WorkersStarted(-2)
+
+			expected = `
+				# HELP reactor_crw_workers Amount of workers started by running clients.
+				# TYPE reactor_crw_workers gauge
+				reactor_crw_workers 0
+				# HELP reactor_crw_workers_busy Amount of workers processing a content source at the moment.
+				# TYPE reactor_crw_workers_busy gauge
+				reactor_crw_workers_busy 0
+			`
+			err := testutil.GatherAndCompare(
+				reg, strings.NewReader(expected),
+				"reactor_crw_workers", "reactor_crw_workers_busy",
+			)
+			require.NoError(t, err)
+		}
+	}
+}
+
+func TestErrorClass(t *testing.T) {
+	t.Log("Given the need to classify errors.")
+	{
+		cases := map[string]error{
+			"cancelled": fmt.Errorf("wrapped: %w", context.Canceled),
+			"timeout":   fmt.Errorf("wrapped: %w", timeoutError{}),
+			"fs":        &fs.PathError{Op: "open", Path: "file", Err: fs.ErrNotExist},
+			"other":     errors.New("error"),
+		}
+
+		for expected, err := range cases {
+			t.Logf("When the error is %q.", err)
+			{
+				require.Equal(t, expected, metrics.ErrorClass(err))
+			}
+		}
+	}
+}
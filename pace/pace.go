@@ -0,0 +1,30 @@
+package pace
+
+import (
+	"sync"
+	"time"
+)
+
+// Pacer spaces out events sharing a rate, like requests or chunks of a
+// download. Each event takes its own amount of time from the rate, so events
+// never happen faster than it allows. It's safe for concurrent use. The zero
+// value is ready to use.
+type Pacer struct {
+	mu   sync.Mutex
+	next time.Time
+}
+
+// Wait reserves the next slot for an event taking d and blocks until the slot
+// comes. The following event can happen d after this one.
+func (p *Pacer) Wait(d time.Duration) {
+	p.mu.Lock()
+	now := time.Now()
+	if p.next.Before(now) {
+		p.next = now
+	}
+	slot := p.next
+	p.next = p.next.Add(d)
+	p.mu.Unlock()
+
+	time.Sleep(time.Until(slot))
+}
@@ -0,0 +1,51 @@
+//go:build unit
+// +build unit
+
+package pace_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"reactor-crw/pace"
+)
+
+func TestPacer_Wait(t *testing.T) {
+	t.Log("Given the need to space out events.")
+	{
+		t.Log("When events are sequential.")
+		{
+			p := &pace.Pacer{}
+
+			start := time.Now()
+			p.Wait(50 * time.Millisecond)
+			require.Less(t, int64(time.Since(start)), int64(50*time.Millisecond), "The first event shouldn't wait")
+
+			p.Wait(50 * time.Millisecond)
+			p.Wait(50 * time.Millisecond)
+			require.GreaterOrEqual(t, int64(time.Since(start)), int64(100*time.Millisecond))
+		}
+
+		t.Log("When events are concurrent.")
+		{
+			p := &pace.Pacer{}
+
+			start := time.Now()
+
+			var wg sync.WaitGroup
+			for i := 0; i < 4; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					p.Wait(30 * time.Millisecond)
+				}()
+			}
+			wg.Wait()
+
+			require.GreaterOrEqual(t, int64(time.Since(start)), int64(90*time.Millisecond))
+		}
+	}
+}
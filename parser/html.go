@@ -5,6 +5,7 @@ import (
 	"github.com/PuerkitoBio/goquery"
 	"io"
 	"net/url"
+	"strings"
 )
 
 // Html represents a HTML parser implementation.
@@ -23,7 +24,8 @@ func (h *Html) FindContent(r io.Reader, query string) (string, error) {
 
 // FindAttrMap parses HTML documents with multiple queries and retrieves the
 // corresponding attributes of found elements. All queries and related attributes
-// are stores within QueryAttrMap. All results will be stored in QueryResult.
+// are stores within QueryAttrMap. All results will be stored in QueryResult
+// along with the query that found them.
 //
 // Example: p.FindAttrMap(body, QueryAttrMap{"div": "class"}, QueryResult{})
 func (h *Html) FindAttrMap(r io.Reader, q QueryAttrMap, res QueryResult) error {
@@ -32,17 +34,56 @@ func (h *Html) FindAttrMap(r io.Reader, q QueryAttrMap, res QueryResult) error {
 		return fmt.Errorf("cannot parse document: %w", err)
 	}
 
+	findAttrMap(doc.Selection, q, res)
+
+	return nil
+}
+
+// FindBlocks searches for all elements by the block query and applies queries
+// within each of them. Attributes are retrieved the same way FindAttrMap does
+// it. Texts are trimmed and empty ones are skipped.
+//
+// Example: p.FindBlocks(body, ".post", QueryAttrMap{"img": "src"}, []string{".tag"})
+func (h *Html) FindBlocks(r io.Reader, block string, q QueryAttrMap, texts []string) ([]Block, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse document: %w", err)
+	}
+
+	var blocks []Block
+	doc.Find(block).Each(func(_ int, s *goquery.Selection) {
+		b := Block{
+			Result: make(QueryResult),
+			Texts:  make(map[string][]string, len(texts)),
+		}
+
+		findAttrMap(s, q, b.Result)
+		for _, query := range texts {
+			s.Find(query).Each(func(_ int, e *goquery.Selection) {
+				if t := strings.TrimSpace(e.Text()); t != "" {
+					b.Texts[query] = append(b.Texts[query], t)
+				}
+			})
+		}
+
+		blocks = append(blocks, b)
+	})
+
+	return blocks, nil
+}
+
+func findAttrMap(s *goquery.Selection, q QueryAttrMap, res QueryResult) {
 	for query, attr := range q {
-		doc.Find(query).Each(func(_ int, s *goquery.Selection) {
+		s.Find(query).Each(func(_ int, s *goquery.Selection) {
 			val, ok := s.Attr(attr)
 			if !ok || val == "javascript:" {
 				return
 			}
 
 			val, _ = url.QueryUnescape(val)
-			res[val] = struct{}{}
+			if _, ok := res[val]; !ok {
+				res[val] = query
+			}
 		})
 	}
-
-	return nil
 }
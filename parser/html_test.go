@@ -53,10 +53,45 @@ func TestHtml_FindAttrMap(t *testing.T) {
 	require.Equal(
 		t,
 		parser.QueryResult{
-			"image-src": struct{}{},
-			"href-test": struct{}{},
-			"div-class": struct{}{},
+			"image-src": "img",
+			"href-test": ".test-class",
+			"div-class": "div",
 		},
 		res,
 	)
 }
+
+func TestHtml_FindBlocks(t *testing.T) {
+	p := parser.Html{}
+
+	r := strings.NewReader(`
+		<body>
+			<div class="post">
+				<span class="tag"> art </span><span class="tag">wallpaper</span>
+				<img src="first-src">
+			</div>
+			<div class="post">
+				<span class="tag"></span>
+				<img src="second-src">
+			</div>
+			<img src="outside-src">
+		</body>
+	`)
+
+	blocks, err := p.FindBlocks(r, ".post", parser.QueryAttrMap{"img": "src"}, []string{".tag"})
+	require.NoErrorf(t, err, "Wasn't expected an error on html parse")
+	require.Equal(
+		t,
+		[]parser.Block{
+			{
+				Result: parser.QueryResult{"first-src": "img"},
+				Texts:  map[string][]string{".tag": {"art", "wallpaper"}},
+			},
+			{
+				Result: parser.QueryResult{"second-src": "img"},
+				Texts:  map[string][]string{},
+			},
+		},
+		blocks,
+	)
+}
@@ -8,8 +8,20 @@ import "io"
 type QueryAttrMap map[string]string
 
 // QueryResult stores all results parsed using the QueryAttrMap value. For preventing
-// data duplicates the values are stored in a map structure.
-type QueryResult map[string]struct{}
+// data duplicates the values are stored in a map structure. Each value is mapped
+// to the query that found it first.
+type QueryResult map[string]string
+
+// Block stores data found within a single element matched by a block query.
+type Block struct {
+	// Result stores attributes found within the block the same way
+	// FindAttrMap stores them.
+	Result QueryResult
+
+	// Texts maps each text query to texts of elements found within the block
+	// in the document order.
+	Texts map[string][]string
+}
 
 // Parser describes a generic set of parser functions.
 type Parser interface {
@@ -20,4 +32,9 @@ type Parser interface {
 	// of found elements. All queries and related attributes stores within QueryAttrMap.
 	// All results will be stored in QueryResult.
 	FindAttrMap(io.Reader, QueryAttrMap, QueryResult) error
+
+	// FindBlocks searches for all elements by the block query and applies
+	// attribute and text queries within each of them separately. It allows
+	// relating found data to the element it belongs to.
+	FindBlocks(r io.Reader, block string, q QueryAttrMap, texts []string) ([]Block, error)
 }
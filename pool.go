@@ -0,0 +1,295 @@
+package reactor_crw
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"reactor-crw/logging"
+)
+
+// ErrEmptyPool returned by PooledTransport without members.
+var ErrEmptyPool = errors.New("transport pool has no members")
+
+// PoolStrategy defines how PooledTransport chooses a transport for a request.
+type PoolStrategy string
+
+const (
+	// RoundRobin uses transports one by one.
+	RoundRobin PoolStrategy = "round-robin"
+
+	// Random uses a random transport.
+	Random PoolStrategy = "random"
+
+	// LeastErrors uses the transport with the least amount of failures.
+	LeastErrors PoolStrategy = "least-errors"
+)
+
+// ParsePoolStrategy converts a strategy name to PoolStrategy.
+func ParsePoolStrategy(s string) (PoolStrategy, error) {
+	switch p := PoolStrategy(s); p {
+	case RoundRobin, Random, LeastErrors:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown pool strategy: %s", s)
+	}
+}
+
+const (
+	defaultMaxFailures = 3
+	defaultCooldown    = time.Minute
+)
+
+// PoolMember is a transport of the pool. Usually it's an HttpTransport using
+// a separate proxy.
+type PoolMember struct {
+	// Name identifies the member in logs. Example: a proxy URL.
+	Name string
+
+	Transport Transport
+}
+
+// PooledTransport distributes requests among multiple transports, so a crawl
+// can be spread across multiple proxies. A transport failing repeatedly is
+// marked unhealthy and isn't used until its cooldown is over. After that it's
+// tested again with the next request. Failed requests are retried with other
+// healthy transports. It's safe for concurrent use.
+type PooledTransport struct {
+	// MaxFailures is the amount of consecutive failures after which a
+	// transport is marked unhealthy. Network errors, 429 and 5xx statuses are
+	// failures. Default value is 3.
+	MaxFailures int
+
+	// Cooldown is the time an unhealthy transport isn't used. Default value
+	// is one minute.
+	Cooldown time.Duration
+
+	// Logger receives a record each time the health of a transport changes.
+	// It's optional.
+	Logger logging.Logger
+
+	strategy PoolStrategy
+	members  []*poolMember
+
+	mu   sync.Mutex
+	next int
+	rnd  *rand.Rand
+}
+
+type poolMember struct {
+	PoolMember
+
+	// failures is the amount of consecutive failures.
+	failures int
+
+	// errors is the total amount of failures.
+	errors int
+
+	// unhealthyUntil is the end of the cooldown of an unhealthy member.
+	unhealthyUntil time.Time
+}
+
+// NewPooledTransport creates a new *PooledTransport choosing members with the
+// strategy.
+func NewPooledTransport(members []PoolMember, s PoolStrategy) *PooledTransport {
+	p := &PooledTransport{
+		strategy: s,
+		rnd:      rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	for _, m := range members {
+		p.members = append(p.members, &poolMember{PoolMember: m})
+	}
+
+	return p
+}
+
+// FetchData fetches the data using a member chosen by the strategy. If the
+// member fails, the request is retried with other members.
+func (p *PooledTransport) FetchData(url string) (io.ReadCloser, error) {
+	if len(p.members) == 0 {
+		return nil, ErrEmptyPool
+	}
+
+	var (
+		body io.ReadCloser
+		err  error
+	)
+
+	p.try(func(t Transport) error {
+		body, err = t.FetchData(url)
+		return err
+	})
+
+	return body, err
+}
+
+// Probe probes the content using a member chosen by the strategy. Members
+// that aren't Prober return ErrProbeUnsupported.
+func (p *PooledTransport) Probe(url string) (ContentInfo, error) {
+	if len(p.members) == 0 {
+		return ContentInfo{}, ErrEmptyPool
+	}
+
+	var (
+		info ContentInfo
+		err  error
+	)
+
+	p.try(func(t Transport) error {
+		pr, ok := t.(Prober)
+		if !ok {
+			err = ErrProbeUnsupported
+			return nil
+		}
+		info, err = pr.Probe(url)
+		return err
+	})
+
+	return info, err
+}
+
+// try calls f with members chosen by the strategy until it succeeds, fails
+// with an error that isn't caused by the member or every member is tried.
+func (p *PooledTransport) try(f func(t Transport) error) {
+	tried := make(map[*poolMember]struct{}, len(p.members))
+
+	for len(tried) < len(p.members) {
+		m := p.pick(tried)
+		tried[m] = struct{}{}
+
+		err := f(m.Transport)
+		failed := isMemberFailure(err)
+		p.report(m, failed)
+
+		if !failed {
+			return
+		}
+	}
+}
+
+// pick chooses a member that isn't tried yet. Healthy members are preferred.
+// If all of them are unhealthy, the one with the earliest end of cooldown is
+// chosen.
+func (p *PooledTransport) pick(tried map[*poolMember]struct{}) *poolMember {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+
+	var healthy, unhealthy []*poolMember
+	for i := range p.members {
+		// Members are listed starting from the round-robin position, so the
+		// order is fair for every strategy.
+		m := p.members[(p.next+i)%len(p.members)]
+		if _, ok := tried[m]; ok {
+			continue
+		}
+		if m.unhealthyUntil.After(now) {
+			unhealthy = append(unhealthy, m)
+		} else {
+			healthy = append(healthy, m)
+		}
+	}
+
+	if len(healthy) == 0 {
+		earliest := unhealthy[0]
+		for _, m := range unhealthy[1:] {
+			if m.unhealthyUntil.Before(earliest.unhealthyUntil) {
+				earliest = m
+			}
+		}
+		return earliest
+	}
+
+	p.next = (p.next + 1) % len(p.members)
+
+	switch p.strategy {
+	case Random:
+		return healthy[p.rnd.Intn(len(healthy))]
+	case LeastErrors:
+		least := healthy[0]
+		for _, m := range healthy[1:] {
+			if m.errors < least.errors {
+				least = m
+			}
+		}
+		return least
+	default:
+		return healthy[0]
+	}
+}
+
+// report updates the health of the member after a request.
+func (p *PooledTransport) report(m *poolMember, failed bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !failed {
+		if !m.unhealthyUntil.IsZero() {
+			m.unhealthyUntil = time.Time{}
+			p.log().Info("pool member recovered", "member", m.Name)
+		}
+		m.failures = 0
+		return
+	}
+
+	m.failures++
+	m.errors++
+
+	if m.failures >= p.maxFailures() {
+		m.unhealthyUntil = time.Now().Add(p.cooldown())
+		p.log().Warn("pool member marked unhealthy", "member", m.Name, "failures", m.failures, "cooldown", p.cooldown())
+	}
+}
+
+func (p *PooledTransport) maxFailures() int {
+	if p.MaxFailures > 0 {
+		return p.MaxFailures
+	}
+
+	return defaultMaxFailures
+}
+
+func (p *PooledTransport) cooldown() time.Duration {
+	if p.Cooldown > 0 {
+		return p.Cooldown
+	}
+
+	return defaultCooldown
+}
+
+func (p *PooledTransport) log() logging.Logger {
+	return logging.OrDiscard(p.Logger)
+}
+
+// isMemberFailure reports whether the error is caused by the member rather
+// than by the requested resource. Only network errors of the round trip, 429
+// and 5xx statuses are treated as failures of the member. Cancelled requests,
+// invalid URLs and stalled bodies aren't.
+func isMemberFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.Code == http.StatusTooManyRequests || se.Code >= http.StatusInternalServerError
+	}
+
+	// Invalid URLs are reported as *url.Error before the request is sent.
+	var ue *url.Error
+	if errors.As(err, &ue) && ue.Op == "parse" {
+		return false
+	}
+
+	var ne net.Error
+
+	return errors.As(err, &ne)
+}
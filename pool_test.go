@@ -0,0 +1,195 @@
+//go:build unit
+// +build unit
+
+package reactor_crw
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePoolStrategy(t *testing.T) {
+	t.Log("Given the need to parse a pool strategy name.")
+	{
+		t.Log("When the name is known.")
+		{
+			s, err := ParsePoolStrategy("least-errors")
+			require.NoError(t, err)
+			require.Equal(t, LeastErrors, s)
+		}
+
+		t.Log("When the name is unknown.")
+		{
+			_, err := ParsePoolStrategy("fastest")
+			require.Error(t, err)
+		}
+	}
+}
+
+func TestPooledTransport_FetchData(t *testing.T) {
+	body := func() io.ReadCloser { return ioutil.NopCloser(strings.NewReader("data")) }
+	refused := &url.Error{Op: "Get", URL: "url", Err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}}
+
+	t.Log("Given the need to distribute requests among transports.")
+	{
+		t.Log("When the round-robin strategy is used.")
+		{
+			first, second := &transportMock{}, &transportMock{}
+			first.On("FetchData", "url").Return(body(), nil).Twice()
+			second.On("FetchData", "url").Return(body(), nil).Twice()
+
+			p := NewPooledTransport([]PoolMember{{Name: "first", Transport: first}, {Name: "second", Transport: second}}, RoundRobin)
+			for i := 0; i < 4; i++ {
+				_, err := p.FetchData("url")
+				require.NoError(t, err, "Wasn't expected an error during fetch")
+			}
+
+			first.AssertExpectations(t)
+			second.AssertExpectations(t)
+		}
+
+		t.Log("When a transport responds with too many requests.")
+		{
+			first, second := &transportMock{}, &transportMock{}
+			first.On("FetchData", "url").Return(body(), &StatusError{URL: "url", Code: 429}).Once()
+			second.On("FetchData", "url").Return(body(), nil).Once()
+
+			p := NewPooledTransport([]PoolMember{{Name: "first", Transport: first}, {Name: "second", Transport: second}}, RoundRobin)
+			_, err := p.FetchData("url")
+			require.NoError(t, err, "The request was expected to be retried with another transport")
+
+			first.AssertExpectations(t)
+			second.AssertExpectations(t)
+		}
+
+		t.Log("When the resource isn't found.")
+		{
+			first, second := &transportMock{}, &transportMock{}
+			first.On("FetchData", "url").Return(body(), &StatusError{URL: "url", Code: 404}).Once()
+
+			p := NewPooledTransport([]PoolMember{{Name: "first", Transport: first}, {Name: "second", Transport: second}}, RoundRobin)
+			_, err := p.FetchData("url")
+
+			var se *StatusError
+			require.True(t, errors.As(err, &se))
+			require.Equal(t, 404, se.Code)
+			second.AssertNotCalled(t, "FetchData", "url")
+		}
+
+		t.Log("When a transport fails repeatedly.")
+		{
+			first, second := &transportMock{}, &transportMock{}
+			first.On("FetchData", "url").Return(body(), refused).Twice()
+			second.On("FetchData", "url").Return(body(), nil).Times(4)
+
+			p := NewPooledTransport([]PoolMember{{Name: "first", Transport: first}, {Name: "second", Transport: second}}, RoundRobin)
+			p.MaxFailures = 2
+			for i := 0; i < 4; i++ {
+				_, err := p.FetchData("url")
+				require.NoError(t, err, "Wasn't expected an error during fetch")
+			}
+
+			// The first transport is unhealthy after two failures, so it isn't
+			// used for the last requests.
+			first.AssertExpectations(t)
+			second.AssertExpectations(t)
+		}
+
+		t.Log("When the cooldown of an unhealthy transport is over.")
+		{
+			first, second := &transportMock{}, &transportMock{}
+			first.On("FetchData", "url").Return(body(), refused).Once()
+			second.On("FetchData", "url").Return(body(), nil).Once()
+
+			p := NewPooledTransport([]PoolMember{{Name: "first", Transport: first}, {Name: "second", Transport: second}}, RoundRobin)
+			p.MaxFailures = 1
+			p.Cooldown = 10 * time.Millisecond
+
+			_, err := p.FetchData("url")
+			require.NoError(t, err)
+
+			time.Sleep(20 * time.Millisecond)
+
+			first.On("FetchData", "url").Return(body(), nil).Once()
+			_, err = p.FetchData("url")
+			require.NoError(t, err)
+
+			first.AssertExpectations(t)
+			second.AssertExpectations(t)
+			require.True(t, p.members[0].unhealthyUntil.IsZero(), "The transport was expected to recover")
+		}
+
+		t.Log("When all transports fail.")
+		{
+			first, second := &transportMock{}, &transportMock{}
+			first.On("FetchData", "url").Return(body(), &StatusError{URL: "url", Code: 503}).Once()
+			second.On("FetchData", "url").Return(body(), &StatusError{URL: "url", Code: 503}).Once()
+
+			p := NewPooledTransport([]PoolMember{{Name: "first", Transport: first}, {Name: "second", Transport: second}}, RoundRobin)
+			_, err := p.FetchData("url")
+			require.Error(t, err)
+
+			first.AssertExpectations(t)
+			second.AssertExpectations(t)
+		}
+
+		t.Log("When a request fails for reasons other than the transport.")
+		{
+			errs := []error{
+				&url.Error{Op: "Get", URL: "url", Err: context.Canceled},
+				&url.Error{Op: "parse", URL: "url", Err: errors.New("invalid URL escape")},
+				&StallError{URL: "url", Timeout: time.Second},
+			}
+
+			for _, e := range errs {
+				first, second := &transportMock{}, &transportMock{}
+				first.On("FetchData", "url").Return(body(), e).Once()
+
+				p := NewPooledTransport([]PoolMember{{Name: "first", Transport: first}, {Name: "second", Transport: second}}, RoundRobin)
+				p.MaxFailures = 1
+
+				_, err := p.FetchData("url")
+				require.ErrorIs(t, err, e)
+				second.AssertNotCalled(t, "FetchData", "url")
+				require.Zero(t, p.members[0].errors, "The transport shouldn't be blamed for %v", e)
+				require.True(t, p.members[0].unhealthyUntil.IsZero())
+			}
+		}
+
+		t.Log("When the pool has no members.")
+		{
+			p := NewPooledTransport(nil, RoundRobin)
+
+			_, err := p.FetchData("url")
+			require.ErrorIs(t, err, ErrEmptyPool)
+
+			_, err = p.Probe("url")
+			require.ErrorIs(t, err, ErrEmptyPool)
+		}
+
+		t.Log("When the least-errors strategy is used.")
+		{
+			first, second := &transportMock{}, &transportMock{}
+			first.On("FetchData", "url").Return(body(), refused).Once()
+			second.On("FetchData", "url").Return(body(), nil).Times(3)
+
+			p := NewPooledTransport([]PoolMember{{Name: "first", Transport: first}, {Name: "second", Transport: second}}, LeastErrors)
+			for i := 0; i < 3; i++ {
+				_, err := p.FetchData("url")
+				require.NoError(t, err)
+			}
+
+			first.AssertExpectations(t)
+			second.AssertExpectations(t)
+		}
+	}
+}
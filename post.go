@@ -0,0 +1,127 @@
+package reactor_crw
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"reactor-crw/parser"
+)
+
+const (
+	// htmlPost matches a single post along with its metadata.
+	htmlPost = ".postContainer"
+
+	// htmlPostTag matches tags of a post.
+	htmlPostTag = ".taglist a"
+
+	// htmlPostRating matches the rating of a post. It's hidden for new posts.
+	htmlPostRating = ".post_rating > span"
+
+	// htmlPostTime matches elements containing the publication time of a post
+	// as a unix timestamp in the data-time attribute.
+	htmlPostTime = ".date [data-time]"
+)
+
+// PostFilter describes posts whose content should be collected. Zero values
+// disable the corresponding checks. Metadata that cannot be found on the page
+// isn't checked.
+type PostFilter struct {
+	// ExcludeTags lists tags of posts that should be skipped. Tags are
+	// case-insensitive.
+	ExcludeTags []string
+
+	// RequireTags lists tags every collected post must have.
+	RequireTags []string
+
+	// MinRating is the minimal rating of collected posts.
+	MinRating float64
+
+	// Since skips posts published before it. It also stops crawling of
+	// multiple pages once all posts on a page are older than it.
+	Since time.Time
+
+	// Until skips posts published at or after it.
+	Until time.Time
+}
+
+// Empty reports whether there are no rules to check.
+func (f PostFilter) Empty() bool {
+	return len(f.ExcludeTags) == 0 && len(f.RequireTags) == 0 && f.MinRating == 0 && f.Since.IsZero() && f.Until.IsZero()
+}
+
+// check returns the reason the post doesn't match the filter or an empty
+// string if it does.
+func (f PostFilter) check(p post) string {
+	tags := make(map[string]struct{}, len(p.tags))
+	for _, t := range p.tags {
+		tags[strings.ToLower(t)] = struct{}{}
+	}
+
+	for _, t := range f.ExcludeTags {
+		if _, ok := tags[strings.ToLower(t)]; ok {
+			return fmt.Sprintf("tag %q is excluded", t)
+		}
+	}
+	for _, t := range f.RequireTags {
+		if _, ok := tags[strings.ToLower(t)]; !ok {
+			return fmt.Sprintf("tag %q is missing", t)
+		}
+	}
+
+	if f.MinRating != 0 && p.rated && p.rating < f.MinRating {
+		return fmt.Sprintf("rating %.1f is less than %.1f", p.rating, f.MinRating)
+	}
+
+	if p.published.IsZero() {
+		return ""
+	}
+	if !f.Since.IsZero() && p.published.Before(f.Since) {
+		return fmt.Sprintf("published %s before %s", p.published.Format(time.RFC3339), f.Since.Format(time.RFC3339))
+	}
+	if !f.Until.IsZero() && !p.published.Before(f.Until) {
+		return fmt.Sprintf("published %s after %s", p.published.Format(time.RFC3339), f.Until.Format(time.RFC3339))
+	}
+
+	return ""
+}
+
+// post contains metadata of a post found on the page.
+type post struct {
+	tags      []string
+	rating    float64
+	rated     bool
+	published time.Time
+}
+
+// parsePost retrieves post metadata from the block found by htmlPost. If the
+// block contains multiple timestamps, the earliest one is the post time since
+// the rest belong to comments.
+func parsePost(b parser.Block) post {
+	p := post{tags: b.Texts[htmlPostTag]}
+
+	if r := b.Texts[htmlPostRating]; len(r) > 0 {
+		if f := strings.Fields(r[0]); len(f) > 0 {
+			rating, err := strconv.ParseFloat(f[0], 64)
+			if err == nil {
+				p.rating, p.rated = rating, true
+			}
+		}
+	}
+
+	for v, q := range b.Result {
+		if q != htmlPostTime {
+			continue
+		}
+		sec, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			continue
+		}
+		if t := time.Unix(sec, 0); p.published.IsZero() || t.Before(p.published) {
+			p.published = t
+		}
+	}
+
+	return p
+}
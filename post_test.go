@@ -0,0 +1,72 @@
+//go:build unit
+// +build unit
+
+package reactor_crw
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"reactor-crw/parser"
+)
+
+func TestPostFilter_check(t *testing.T) {
+	published := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	p := post{tags: []string{"Art", "wallpaper"}, rating: 10, rated: true, published: published}
+
+	t.Log("Given the need to filter posts.")
+	{
+		t.Log("When the post matches all rules.")
+		{
+			f := PostFilter{
+				ExcludeTags: []string{"spam"},
+				RequireTags: []string{"art"},
+				MinRating:   5,
+				Since:       published.AddDate(0, -1, 0),
+			}
+			require.Empty(t, f.check(p))
+		}
+
+		t.Log("When the post doesn't match a rule.")
+		{
+			filters := []PostFilter{
+				{ExcludeTags: []string{"ART"}},
+				{RequireTags: []string{"art", "anime"}},
+				{MinRating: 15},
+				{Since: published.AddDate(0, 0, 1)},
+				{Until: published},
+			}
+			for _, f := range filters {
+				require.NotEmpty(t, f.check(p), "Expected the post to be skipped by %+v", f)
+			}
+		}
+
+		t.Log("When the post metadata is unknown.")
+		{
+			f := PostFilter{MinRating: 15, Since: published, Until: published}
+			require.Empty(t, f.check(post{}))
+		}
+	}
+}
+
+func TestParsePost(t *testing.T) {
+	b := parser.Block{
+		Result: parser.QueryResult{
+			"1709251200": htmlPostTime,
+			"1709337600": htmlPostTime,
+			"link_1":     ".post_content .image > img",
+		},
+		Texts: map[string][]string{
+			htmlPostTag:    {"art", "wallpaper"},
+			htmlPostRating: {"12.5 +"},
+		},
+	}
+
+	p := parsePost(b)
+	require.Equal(t, []string{"art", "wallpaper"}, p.tags)
+	require.True(t, p.rated)
+	require.Equal(t, 12.5, p.rating)
+	require.Equal(t, int64(1709251200), p.published.Unix())
+}
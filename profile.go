@@ -0,0 +1,119 @@
+package reactor_crw
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultProfile is the name of the header profile used by HttpTransport
+// unless another one is chosen.
+const DefaultProfile = "firefox"
+
+// HeaderProfile describes headers sent by a browser, so requests of the
+// crawler look like requests of the browser.
+type HeaderProfile struct {
+	// UserAgents lists User-Agent values of the browser on different
+	// platforms. The first one is used unless they're rotated.
+	UserAgents []string
+
+	// Headers contains other headers sent by the browser.
+	Headers Headers
+}
+
+// referer is sent by all profiles, since the media CDN rejects requests
+// without it.
+const referer = "http://joyreactor.cc/"
+
+var profiles = map[string]HeaderProfile{
+	"chrome": {
+		UserAgents: []string{
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+			"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+		},
+		Headers: Headers{
+			"Accept":          "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8",
+			"Accept-Language": "en-US,en;q=0.9",
+			"Referer":         referer,
+			"DNT":             "1",
+		},
+	},
+	"firefox": {
+		UserAgents: []string{
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:125.0) Gecko/20100101 Firefox/125.0",
+			"Mozilla/5.0 (Macintosh; Intel Mac OS X 14.4; rv:125.0) Gecko/20100101 Firefox/125.0",
+			"Mozilla/5.0 (X11; Linux x86_64; rv:125.0) Gecko/20100101 Firefox/125.0",
+		},
+		Headers: Headers{
+			"Accept":          "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8",
+			"Accept-Language": "en-US,en;q=0.5",
+			"Referer":         referer,
+			"DNT":             "1",
+		},
+	},
+	"mobile": {
+		UserAgents: []string{
+			"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36",
+			"Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1",
+		},
+		Headers: Headers{
+			"Accept":          "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+			"Accept-Language": "en-US,en;q=0.9",
+			"Referer":         referer,
+			"DNT":             "1",
+		},
+	},
+}
+
+// Profile returns the header profile by its name.
+func Profile(name string) (HeaderProfile, error) {
+	p, ok := profiles[name]
+	if !ok {
+		return HeaderProfile{}, fmt.Errorf("unknown header profile %q, possible values: %s", name, strings.Join(ProfileNames(), ","))
+	}
+
+	return p, nil
+}
+
+// ProfileNames returns names of all header profiles in sorted order.
+func ProfileNames() []string {
+	names := make([]string, 0, len(profiles))
+	for n := range profiles {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Values returns headers of the profile with the first User-Agent.
+func (p HeaderProfile) Values() Headers {
+	res := p.Headers.With(nil)
+	if len(p.UserAgents) > 0 {
+		res["User-Agent"] = p.UserAgents[0]
+	}
+
+	return res
+}
+
+// HeaderRule overrides headers of requests to matching hosts.
+type HeaderRule struct {
+	// Hosts lists host patterns. A pattern starting with "*." matches the
+	// domain and all its subdomains. Example: joyreactor.cc, *.joyreactor.cc.
+	Hosts []string
+
+	Headers Headers
+}
+
+// matches reports whether the rule applies to the host.
+func (r HeaderRule) matches(host string) bool {
+	host = strings.ToLower(host)
+	for _, h := range r.Hosts {
+		if matchHost(strings.ToLower(h), host) {
+			return true
+		}
+	}
+
+	return false
+}
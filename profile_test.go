@@ -0,0 +1,40 @@
+//go:build unit
+// +build unit
+
+package reactor_crw_test
+
+import (
+	"testing"
+
+	"reactor-crw"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfile(t *testing.T) {
+	t.Log("Given the need to choose a header profile.")
+	{
+		t.Log("When the profile exists.")
+		{
+			p, err := reactor_crw.Profile("mobile")
+			require.NoError(t, err)
+
+			h := p.Values()
+			require.Equal(t, p.UserAgents[0], h["User-Agent"])
+			require.Equal(t, "http://joyreactor.cc/", h["Referer"])
+			require.Equal(t, "1", h["DNT"])
+			require.NotContains(t, p.Headers, "User-Agent", "Profile headers shouldn't be changed")
+		}
+
+		t.Log("When the profile doesn't exist.")
+		{
+			_, err := reactor_crw.Profile("netscape")
+			require.Error(t, err)
+		}
+
+		t.Log("When all profiles are listed.")
+		{
+			require.Equal(t, []string{"chrome", "firefox", "mobile"}, reactor_crw.ProfileNames())
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// Package reactortest runs the crawler end-to-end against fixture archives
+// recorded with reactor_crw.RecordingTransport, so tests of selectors and
+// filters don't need network access.
+package reactortest
+
+import (
+	"sort"
+	"sync"
+	"testing"
+
+	"reactor-crw"
+	"reactor-crw/handler"
+	"reactor-crw/parser"
+)
+
+// Transport opens the fixture archive in the directory. The test fails if the
+// archive cannot be read.
+func Transport(tb testing.TB, dir string) *reactor_crw.ReplayTransport {
+	tb.Helper()
+
+	t, err := reactor_crw.NewReplayTransport(dir)
+	if err != nil {
+		tb.Fatalf("cannot open fixture archive: %s", err)
+	}
+
+	return t
+}
+
+// Crawler creates an HtmlCrawler crawling pages of the fixture archive in the
+// directory with the HTML parser. Its fields can be changed before it's used.
+func Crawler(tb testing.TB, dir string) *reactor_crw.HtmlCrawler {
+	tb.Helper()
+
+	return &reactor_crw.HtmlCrawler{
+		Transport: Transport(tb, dir),
+		Parser:    &parser.Html{},
+		MultiPage: true,
+	}
+}
+
+// Collector is a handler.ContentHandler keeping all processed sources
+// without downloading them. It's safe for concurrent use.
+type Collector struct {
+	mu      sync.Mutex
+	sources []handler.Source
+}
+
+// Process keeps the source.
+func (c *Collector) Process(s handler.Source, progress chan<- int, _ chan<- error) {
+	c.mu.Lock()
+	c.sources = append(c.sources, s)
+	c.mu.Unlock()
+
+	progress <- 1
+}
+
+// Sources returns processed sources sorted by URL.
+func (c *Collector) Sources() []handler.Source {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	res := make([]handler.Source, len(c.sources))
+	copy(res, c.sources)
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].URL < res[j].URL
+	})
+
+	return res
+}
+
+// Run runs the client and returns errors of processed sources. The test
+// fails if the run fails.
+func Run(tb testing.TB, c *reactor_crw.Client, path, search string) []error {
+	tb.Helper()
+
+	var (
+		errs []error
+		done = make(chan struct{})
+	)
+
+	go func() {
+		defer close(done)
+
+		progress, errors := c.Progress, c.Errors
+		for progress != nil || errors != nil {
+			select {
+			case _, ok := <-progress:
+				if !ok {
+					progress = nil
+				}
+			case err, ok := <-errors:
+				if !ok {
+					errors = nil
+					continue
+				}
+				errs = append(errs, err)
+			}
+		}
+	}()
+
+	err := c.Run(path, search)
+	<-done
+
+	if err != nil {
+		tb.Fatalf("cannot run client: %s", err)
+	}
+
+	return errs
+}
+
+// Collect crawls the path with the crawler using a client with a Collector
+// and returns all found sources sorted by URL. The test fails if the run or
+// processing of any source fails.
+func Collect(tb testing.TB, c reactor_crw.Crawler, path, search string) []handler.Source {
+	tb.Helper()
+
+	col := &Collector{}
+	if errs := Run(tb, reactor_crw.NewClient(c, 1, col), path, search); len(errs) > 0 {
+		tb.Fatalf("cannot process sources: %v", errs)
+	}
+
+	return col.Sources()
+}
@@ -0,0 +1,77 @@
+//go:build unit
+// +build unit
+
+package reactortest_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"reactor-crw"
+	"reactor-crw/handler"
+	"reactor-crw/handler/fs"
+	"reactor-crw/reactortest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollect(t *testing.T) {
+	t.Log("Given the need to crawl recorded pages.")
+	{
+		t.Log("When all pages are crawled.")
+		{
+			sources := reactortest.Collect(t, reactortest.Crawler(t, "testdata/art"), "http://joyreactor.cc/tag/art", "image,gif")
+
+			require.Equal(t, []handler.Source{
+				{URL: "http://img10.joyreactor.cc/pics/post/art-1.jpeg", Type: "image", Page: "http://joyreactor.cc/tag/art/1"},
+				{URL: "http://img10.joyreactor.cc/pics/post/art-2.jpeg", Type: "image", Page: "http://joyreactor.cc/tag/art/2"},
+				{URL: "http://img10.joyreactor.cc/pics/post/art-3.gif", Type: "gif", Page: "http://joyreactor.cc/tag/art/2"},
+			}, sources)
+		}
+
+		t.Log("When only the last page is crawled.")
+		{
+			c := reactortest.Crawler(t, "testdata/art")
+			c.FirstPage = 2
+
+			sources := reactortest.Collect(t, c, "http://joyreactor.cc/tag/art", "image")
+			require.Len(t, sources, 1)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	t.Log("Given the need to download recorded content.")
+	{
+		t.Log("When content is saved by the file saver.")
+		{
+			// The file saver changes the working directory, so the archive
+			// path has to be absolute.
+			archive, err := filepath.Abs("testdata/art")
+			require.NoError(t, err)
+			wd, err := os.Getwd()
+			require.NoError(t, err)
+			defer func() {
+				_ = os.Chdir(wd)
+			}()
+
+			dir := t.TempDir()
+			pr, err := fs.NewPathResolver(dir)
+			require.NoError(t, err)
+			fsh, err := fs.NewFileSaver(pr, reactortest.Transport(t, archive), "art")
+			require.NoError(t, err)
+
+			c := reactor_crw.NewClient(reactortest.Crawler(t, archive), 2, fsh)
+			errs := reactortest.Run(t, c, "http://joyreactor.cc/tag/art", "image,gif")
+
+			// art-2.jpeg isn't recorded and art-3.gif is recorded as missing.
+			require.Len(t, errs, 2)
+
+			data, err := ioutil.ReadFile(filepath.Join(dir, "art", "art-1.jpeg"))
+			require.NoError(t, err)
+			require.Equal(t, "jpeg", string(data))
+		}
+	}
+}
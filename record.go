@@ -0,0 +1,363 @@
+package reactor_crw
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"reactor-crw/atomicfile"
+	"reactor-crw/logging"
+)
+
+// ErrNotRecorded returned by ReplayTransport for requests missing in the
+// fixture archive.
+var ErrNotRecorded = errors.New("not recorded")
+
+// fixtureIndex is the name of the index file of a fixture archive.
+const fixtureIndex = "index.json"
+
+// Fixture describes a recorded response. A fixture archive is a directory
+// with the index.json file listing fixtures and a file for the body of each
+// of them, so fixtures can be reviewed and edited by hand.
+type Fixture struct {
+	// URL is the requested URL.
+	URL string `json:"url"`
+
+	// File is the name of the body file within the archive. It's empty for
+	// error responses.
+	File string `json:"file,omitempty"`
+
+	// Status is the status of an error response. It's zero for successful
+	// responses.
+	Status int `json:"status,omitempty"`
+}
+
+// RecordingTransport wraps a Transport and writes every response to a fixture
+// archive, so it can be served by ReplayTransport later. A body is written
+// when it's read till the end and closed. Error responses are recorded with
+// their status. The index of the archive is written by Flush or Close. It's
+// safe for concurrent use.
+type RecordingTransport struct {
+	// Logger receives a record for each recorded response. It's optional.
+	Logger logging.Logger
+
+	t   Transport
+	dir string
+
+	mu       sync.Mutex
+	fixtures map[string]Fixture
+	dirty    bool
+}
+
+// NewRecordingTransport creates a new *RecordingTransport writing to the
+// directory. Fixtures which are already in the archive are kept unless the
+// same URL is recorded again.
+func NewRecordingTransport(t Transport, dir string) (*RecordingTransport, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("cannot create fixture archive: %w", err)
+	}
+
+	fixtures, err := readFixtures(dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if fixtures == nil {
+		fixtures = make(map[string]Fixture)
+	}
+
+	return &RecordingTransport{
+		t:        t,
+		dir:      dir,
+		fixtures: fixtures,
+	}, nil
+}
+
+// FetchData fetches the data using the wrapped transport and records the
+// response.
+func (t *RecordingTransport) FetchData(url string) (io.ReadCloser, error) {
+	body, err := t.t.FetchData(url)
+
+	var se *StatusError
+	if errors.As(err, &se) {
+		t.add(Fixture{URL: url, Status: se.Code})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := ioutil.TempFile(t.dir, ".tmp-")
+	if err != nil {
+		t.log().Warn("cannot record response", "url", url, "error", err)
+		return body, nil
+	}
+
+	return &recordBody{ReadCloser: body, url: url, tmp: tmp, t: t}, nil
+}
+
+// Probe probes the content using the wrapped transport. Probes aren't
+// recorded. ErrProbeUnsupported is returned if the wrapped transport isn't a
+// Prober.
+func (t *RecordingTransport) Probe(url string) (ContentInfo, error) {
+	p, ok := t.t.(Prober)
+	if !ok {
+		return ContentInfo{}, ErrProbeUnsupported
+	}
+
+	return p.Probe(url)
+}
+
+// Flush writes the index of the archive if fixtures were recorded since the
+// last write.
+func (t *RecordingTransport) Flush() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.dirty {
+		return nil
+	}
+
+	list := make([]Fixture, 0, len(t.fixtures))
+	for _, f := range t.fixtures {
+		list = append(list, f)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].URL < list[j].URL
+	})
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = atomicfile.Write(filepath.Join(t.dir, fixtureIndex), bytes.NewReader(data), 0644); err != nil {
+		return fmt.Errorf("cannot write fixture archive: %w", err)
+	}
+	t.dirty = false
+
+	return nil
+}
+
+// Close writes the index of the archive. Fixtures recorded after closing are
+// written by the next Flush.
+func (t *RecordingTransport) Close() error {
+	return t.Flush()
+}
+
+// add adds the fixture to the index.
+func (t *RecordingTransport) add(f Fixture) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.fixtures[f.URL] = f
+	t.dirty = true
+}
+
+func (t *RecordingTransport) log() logging.Logger {
+	return logging.OrDiscard(t.Logger)
+}
+
+// recordBody copies the body to a temporary file while it's read. The file
+// becomes a fixture when the body is read till the end and closed.
+type recordBody struct {
+	io.ReadCloser
+	url string
+	tmp *os.File
+	t   *RecordingTransport
+
+	complete bool
+	failed   bool
+}
+
+func (b *recordBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 && !b.failed {
+		if _, werr := b.tmp.Write(p[:n]); werr != nil {
+			b.failed = true
+		}
+	}
+	if err == io.EOF {
+		b.complete = true
+	}
+
+	return n, err
+}
+
+func (b *recordBody) Close() error {
+	err := b.ReadCloser.Close()
+
+	if cerr := b.tmp.Close(); cerr != nil {
+		b.failed = true
+	}
+	if !b.complete || b.failed {
+		_ = os.Remove(b.tmp.Name())
+		return err
+	}
+
+	name := fixtureName(b.url)
+	if rerr := os.Rename(b.tmp.Name(), filepath.Join(b.t.dir, name)); rerr != nil {
+		_ = os.Remove(b.tmp.Name())
+		b.t.log().Warn("cannot record response", "url", b.url, "error", rerr)
+		return err
+	}
+
+	b.t.add(Fixture{URL: b.url, File: name})
+	b.t.log().Debug("response recorded", "url", b.url, "file", name)
+
+	return err
+}
+
+// ReplayTransport serves responses from a fixture archive written by
+// RecordingTransport without making requests. ErrNotRecorded is returned for
+// requests missing in the archive. It's safe for concurrent use.
+type ReplayTransport struct {
+	dir      string
+	fixtures map[string]Fixture
+}
+
+// NewReplayTransport creates a new *ReplayTransport reading the fixture
+// archive in the directory.
+func NewReplayTransport(dir string) (*ReplayTransport, error) {
+	fixtures, err := readFixtures(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReplayTransport{
+		dir:      dir,
+		fixtures: fixtures,
+	}, nil
+}
+
+// FetchData returns the recorded body of the URL. *StatusError is returned if
+// an error response was recorded.
+func (t *ReplayTransport) FetchData(url string) (io.ReadCloser, error) {
+	return t.open(url)
+}
+
+// open opens the body file of the URL.
+func (t *ReplayTransport) open(url string) (*os.File, error) {
+	f, ok := t.fixtures[url]
+	if !ok {
+		return nil, fmt.Errorf("cannot fetch %s: %w", url, ErrNotRecorded)
+	}
+
+	if f.Status != 0 {
+		return nil, &StatusError{URL: url, Code: f.Status}
+	}
+
+	p, err := t.fixturePath(f)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read fixture of %s: %w", url, err)
+	}
+
+	body, err := os.Open(p)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read fixture of %s: %w", url, err)
+	}
+
+	return body, nil
+}
+
+// fixturePath returns the path of the body file of the fixture. Files outside
+// the archive are rejected, since the index may come from another machine.
+func (t *ReplayTransport) fixturePath(f Fixture) (string, error) {
+	if f.File == "" || filepath.IsAbs(f.File) {
+		return "", fmt.Errorf("invalid fixture file %q", f.File)
+	}
+
+	p := filepath.Join(t.dir, f.File)
+	rel, err := filepath.Rel(t.dir, p)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("fixture file %q is outside the archive", f.File)
+	}
+
+	return p, nil
+}
+
+// Probe returns information about the recorded body of the URL. The length is
+// the size of the body file and the type is detected from its content.
+// *StatusError is returned if an error response was recorded.
+func (t *ReplayTransport) Probe(url string) (ContentInfo, error) {
+	f, err := t.open(url)
+	if err != nil {
+		return ContentInfo{}, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return ContentInfo{}, fmt.Errorf("cannot read fixture of %s: %w", url, err)
+	}
+
+	header := make([]byte, 512)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return ContentInfo{}, fmt.Errorf("cannot read fixture of %s: %w", url, err)
+	}
+
+	return ContentInfo{
+		Type:   http.DetectContentType(header[:n]),
+		Length: stat.Size(),
+	}, nil
+}
+
+// URLs returns recorded URLs in sorted order.
+func (t *ReplayTransport) URLs() []string {
+	urls := make([]string, 0, len(t.fixtures))
+	for u := range t.fixtures {
+		urls = append(urls, u)
+	}
+	sort.Strings(urls)
+
+	return urls
+}
+
+// readFixtures reads the index of the fixture archive in the directory.
+func readFixtures(dir string) (map[string]Fixture, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, fixtureIndex))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read fixture archive: %w", err)
+	}
+
+	var list []Fixture
+	if err = json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("cannot parse fixture archive %s: %w", dir, err)
+	}
+
+	fixtures := make(map[string]Fixture, len(list))
+	for _, f := range list {
+		fixtures[f.URL] = f
+	}
+
+	return fixtures, nil
+}
+
+// fixtureName returns the name of the body file of the URL. It keeps the
+// extension of the URL path, so fixtures are easy to open, and ".html" is
+// used for pages without one.
+func fixtureName(rawURL string) string {
+	sum := sha256.Sum256([]byte(rawURL))
+	name := hex.EncodeToString(sum[:8])
+
+	ext := ".html"
+	if u, err := url.Parse(rawURL); err == nil && path.Ext(u.Path) != "" {
+		ext = path.Ext(u.Path)
+	}
+
+	return name + ext
+}
@@ -0,0 +1,133 @@
+//go:build unit
+// +build unit
+
+package reactor_crw
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordingTransport_FetchData(t *testing.T) {
+	body := func(s string) io.ReadCloser { return ioutil.NopCloser(strings.NewReader(s)) }
+
+	t.Log("Given the need to record responses and replay them.")
+	{
+		dir := t.TempDir()
+
+		trp := &transportMock{}
+		trp.On("FetchData", "http://joyreactor.cc/tag/art").Return(body("page"), nil).Once()
+		trp.On("FetchData", "http://joyreactor.cc/tag/art/2").Return(body("second"), nil).Once()
+		trp.On("FetchData", "http://joyreactor.cc/tag/missing").Return(body(""), &StatusError{URL: "http://joyreactor.cc/tag/missing", Code: 404}).Once()
+
+		rt, err := NewRecordingTransport(trp, dir)
+		require.NoError(t, err)
+
+		t.Log("When responses are read till the end.")
+		{
+			rc, err := rt.FetchData("http://joyreactor.cc/tag/art")
+			require.NoError(t, err)
+			data, err := ioutil.ReadAll(rc)
+			require.NoError(t, err)
+			require.Equal(t, "page", string(data))
+			require.NoError(t, rc.Close())
+
+			_, err = rt.FetchData("http://joyreactor.cc/tag/missing")
+			require.Error(t, err)
+		}
+
+		t.Log("When a response isn't read till the end.")
+		{
+			rc, err := rt.FetchData("http://joyreactor.cc/tag/art/2")
+			require.NoError(t, err)
+			require.NoError(t, rc.Close())
+		}
+
+		t.Log("When the recorder isn't closed yet.")
+		{
+			_, err := NewReplayTransport(dir)
+			require.True(t, errors.Is(err, os.ErrNotExist), "The index should be written on close only")
+
+			require.NoError(t, rt.Close())
+		}
+
+		trp.AssertExpectations(t)
+
+		t.Log("When recorded responses are replayed.")
+		{
+			rp, err := NewReplayTransport(dir)
+			require.NoError(t, err)
+			require.Equal(t, []string{"http://joyreactor.cc/tag/art", "http://joyreactor.cc/tag/missing"}, rp.URLs())
+
+			rc, err := rp.FetchData("http://joyreactor.cc/tag/art")
+			require.NoError(t, err)
+			data, err := ioutil.ReadAll(rc)
+			require.NoError(t, err)
+			require.Equal(t, "page", string(data))
+			require.NoError(t, rc.Close())
+
+			_, err = rp.FetchData("http://joyreactor.cc/tag/missing")
+			var se *StatusError
+			require.True(t, errors.As(err, &se))
+			require.Equal(t, 404, se.Code)
+
+			_, err = rp.FetchData("http://joyreactor.cc/tag/art/2")
+			require.True(t, errors.Is(err, ErrNotRecorded), "Partially read responses shouldn't be recorded")
+
+			info, err := rp.Probe("http://joyreactor.cc/tag/art")
+			require.NoError(t, err)
+			require.Equal(t, ContentInfo{Type: "text/plain; charset=utf-8", Length: 4}, info)
+
+			_, err = rp.Probe("http://joyreactor.cc/tag/missing")
+			require.True(t, errors.As(err, &se))
+		}
+
+		t.Log("When the archive is recorded again.")
+		{
+			trp := &transportMock{}
+			trp.On("FetchData", "http://joyreactor.cc/tag/art/2").Return(body("second"), nil).Once()
+
+			rt, err := NewRecordingTransport(trp, dir)
+			require.NoError(t, err)
+
+			rc, err := rt.FetchData("http://joyreactor.cc/tag/art/2")
+			require.NoError(t, err)
+			_, _ = ioutil.ReadAll(rc)
+			require.NoError(t, rc.Close())
+			require.NoError(t, rt.Close())
+
+			rp, err := NewReplayTransport(dir)
+			require.NoError(t, err)
+			require.Len(t, rp.URLs(), 3, "Existing fixtures should be kept")
+		}
+
+		t.Log("When fixture files point outside the archive.")
+		{
+			root := t.TempDir()
+			archive := filepath.Join(root, "archive")
+			require.NoError(t, os.Mkdir(archive, 0700))
+			require.NoError(t, ioutil.WriteFile(filepath.Join(root, "secret"), []byte("secret"), 0600))
+
+			index := `[{"url": "relative", "file": "../secret"}, {"url": "absolute", "file": "` + filepath.Join(root, "secret") + `"}]`
+			require.NoError(t, ioutil.WriteFile(filepath.Join(archive, fixtureIndex), []byte(index), 0600))
+
+			rp, err := NewReplayTransport(archive)
+			require.NoError(t, err)
+
+			for _, u := range []string{"relative", "absolute"} {
+				_, err = rp.FetchData(u)
+				require.Error(t, err, "Expected %s fixture to be rejected", u)
+
+				_, err = rp.Probe(u)
+				require.Error(t, err, "Expected %s fixture to be rejected", u)
+			}
+		}
+	}
+}
@@ -0,0 +1,429 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"reactor-crw"
+	"reactor-crw/event"
+)
+
+var (
+	// ErrJobNotFound returned when there is no job with the requested id.
+	ErrJobNotFound = errors.New("job not found")
+
+	// ErrJobFinished returned on cancelling a job that isn't running anymore.
+	ErrJobFinished = errors.New("job already finished")
+
+	// ErrInvalidRequest returned when a job request is incomplete or invalid.
+	ErrInvalidRequest = errors.New("invalid request")
+
+	// ErrTooManyJobs returned on submitting a job while Manager.MaxJobs jobs
+	// are running.
+	ErrTooManyJobs = errors.New("too many running jobs")
+)
+
+// Defaults of Manager limits.
+const (
+	DefaultMaxJobs    = 4
+	DefaultMaxWorkers = 16
+	DefaultKeepJobs   = 100
+	DefaultMaxErrors  = 100
+)
+
+// DefaultHosts lists host patterns of the sites jobs may crawl by default.
+var DefaultHosts = []string{"*.joyreactor.cc", "*.reactor.cc", "*.joyreactor.com"}
+
+// State describes the state of a job.
+type State string
+
+const (
+	StateRunning   State = "running"
+	StateFinished  State = "finished"
+	StateFailed    State = "failed"
+	StateCancelled State = "cancelled"
+)
+
+// Request describes a crawl job submitted to the server.
+type Request struct {
+	Path        string   `json:"path"`
+	Search      []string `json:"search"`
+	SinglePage  bool     `json:"single_page"`
+	FollowPosts bool     `json:"follow_posts"`
+	Comments    bool     `json:"comments"`
+	Workers     int      `json:"workers"`
+}
+
+// Status describes the current progress of a job.
+type Status struct {
+	ID         string     `json:"id"`
+	Request    Request    `json:"request"`
+	State      State      `json:"state"`
+	Total      int        `json:"total"`
+	Done       int        `json:"done"`
+	Failed     int        `json:"failed"`
+	Errors     []string   `json:"errors"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// ClientFactory creates a new crawler client for the job request. The listener
+// should receive events of the client and its crawler.
+type ClientFactory func(r Request, l event.Listener) (*reactor_crw.Client, error)
+
+// Manager runs crawl jobs in the background and keeps track of their progress.
+// Each job is run by its own reactor_crw.Client.
+type Manager struct {
+	// MaxJobs limits the amount of jobs running at once. Requests beyond the
+	// limit are rejected with ErrTooManyJobs. Zero means no limit.
+	MaxJobs int
+
+	// Hosts lists host patterns job paths may point to. A pattern starting
+	// with "*." matches the domain and all its subdomains. Empty allows any
+	// host.
+	Hosts []string
+
+	// MaxWorkers caps the amount of workers of a job. Requests asking for
+	// more workers get this amount. Zero means no limit.
+	MaxWorkers int
+
+	// KeepJobs limits the amount of finished jobs kept by the manager. The
+	// oldest finished jobs are forgotten first. Zero means no limit.
+	KeepJobs int
+
+	// MaxErrors limits the amount of errors kept in the status of a job.
+	// Errors beyond the limit are only counted in Status.Failed. Zero means
+	// no limit.
+	MaxErrors int
+
+	newClient ClientFactory
+
+	mu     sync.Mutex
+	lastID int
+	jobs   map[string]*job
+}
+
+type job struct {
+	status Status
+	cancel context.CancelFunc
+	events *event.Broker
+}
+
+// NewManager creates a new *Manager that uses the factory to create clients
+// for submitted jobs. Limits of the manager are set to their defaults.
+func NewManager(f ClientFactory) *Manager {
+	return &Manager{
+		MaxJobs:    DefaultMaxJobs,
+		Hosts:      DefaultHosts,
+		MaxWorkers: DefaultMaxWorkers,
+		KeepJobs:   DefaultKeepJobs,
+		MaxErrors:  DefaultMaxErrors,
+		newClient:  f,
+		jobs:       make(map[string]*job),
+	}
+}
+
+// Submit validates the request and starts a new job in the background.
+func (m *Manager) Submit(r Request) (Status, error) {
+	u, err := url.ParseRequestURI(r.Path)
+	if err != nil || u.Host == "" {
+		return Status{}, fmt.Errorf("%w: invalid path %q", ErrInvalidRequest, r.Path)
+	}
+	if !m.allowed(u.Hostname()) {
+		return Status{}, fmt.Errorf("%w: host %q isn't allowed", ErrInvalidRequest, u.Hostname())
+	}
+
+	if len(r.Search) == 0 {
+		r.Search = []string{"image", "gif"}
+	}
+
+	if r.Workers < 1 {
+		r.Workers = 1
+	}
+	if m.MaxWorkers > 0 && r.Workers > m.MaxWorkers {
+		r.Workers = m.MaxWorkers
+	}
+
+	events := event.NewBroker()
+
+	c, err := m.newClient(r, events)
+	if err != nil {
+		return Status{}, err
+	}
+
+	m.mu.Lock()
+	if m.MaxJobs > 0 && m.runningJobs() >= m.MaxJobs {
+		m.mu.Unlock()
+		return Status{}, ErrTooManyJobs
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	m.lastID++
+	j := &job{
+		status: Status{
+			ID:        strconv.Itoa(m.lastID),
+			Request:   r,
+			State:     StateRunning,
+			Errors:    []string{},
+			StartedAt: time.Now(),
+		},
+		cancel: cancel,
+		events: events,
+	}
+	m.jobs[j.status.ID] = j
+	status := j.status
+	m.mu.Unlock()
+
+	go m.run(ctx, j, c)
+
+	return status, nil
+}
+
+// List returns statuses of all jobs ordered by their ids.
+func (m *Manager) List() []Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	res := make([]Status, 0, len(m.jobs))
+	for _, j := range m.jobs {
+		res = append(res, j.copyStatus())
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		a, _ := strconv.Atoi(res[i].ID)
+		b, _ := strconv.Atoi(res[j].ID)
+		return a < b
+	})
+
+	return res
+}
+
+// Get returns the status of the job by its id.
+func (m *Manager) Get(id string) (Status, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, ok := m.jobs[id]
+	if !ok {
+		return Status{}, ErrJobNotFound
+	}
+
+	return j.copyStatus(), nil
+}
+
+// Subscribe returns a channel of the job events and a function that cancels
+// the subscription. The channel is closed when the job is finished.
+func (m *Manager) Subscribe(id string) (<-chan event.Event, func(), error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, ok := m.jobs[id]
+	if !ok {
+		return nil, nil, ErrJobNotFound
+	}
+
+	ch, cancel := j.events.Subscribe()
+
+	return ch, cancel, nil
+}
+
+// Cancel stops the running job by its id. Content that is already being
+// downloaded will be finished.
+func (m *Manager) Cancel(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, ok := m.jobs[id]
+	if !ok {
+		return ErrJobNotFound
+	}
+
+	if j.status.State != StateRunning {
+		return ErrJobFinished
+	}
+
+	j.cancel()
+
+	return nil
+}
+
+// CancelAll stops all running jobs. Content that is already being downloaded
+// will be finished.
+func (m *Manager) CancelAll() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, j := range m.jobs {
+		if j.status.State == StateRunning {
+			j.cancel()
+		}
+	}
+}
+
+// Wait blocks until all running jobs are finished or the context is done.
+func (m *Manager) Wait(ctx context.Context) error {
+	t := time.NewTicker(100 * time.Millisecond)
+	defer t.Stop()
+
+	for {
+		if !m.running() {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+func (m *Manager) running() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.runningJobs() > 0
+}
+
+// runningJobs returns the amount of running jobs. The caller must hold m.mu.
+func (m *Manager) runningJobs() int {
+	n := 0
+	for _, j := range m.jobs {
+		if j.status.State == StateRunning {
+			n++
+		}
+	}
+
+	return n
+}
+
+// allowed reports whether jobs may crawl the host.
+func (m *Manager) allowed(host string) bool {
+	if len(m.Hosts) == 0 {
+		return true
+	}
+
+	host = strings.ToLower(host)
+	for _, p := range m.Hosts {
+		p = strings.ToLower(p)
+		if p == host || strings.HasPrefix(p, "*.") && (host == p[2:] || strings.HasSuffix(host, p[1:])) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// run runs the client and updates the job status according to the client
+// channels until the client is finished.
+func (m *Manager) run(ctx context.Context, j *job, c *reactor_crw.Client) {
+	defer j.cancel()
+	defer j.events.Close()
+
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- c.RunContext(ctx, j.status.Request.Path, strings.Join(j.status.Request.Search, ","))
+	}()
+
+	total := <-c.TotalSources
+	m.update(j, func(s *Status) {
+		s.Total = total
+	})
+
+	progress, errs := c.Progress, c.Errors
+	for progress != nil || errs != nil {
+		select {
+		case _, ok := <-progress:
+			if !ok {
+				progress = nil
+				continue
+			}
+			m.update(j, func(s *Status) {
+				s.Done++
+			})
+		case e, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			if e != nil {
+				m.update(j, func(s *Status) {
+					s.Failed++
+					if m.MaxErrors <= 0 || len(s.Errors) < m.MaxErrors {
+						s.Errors = append(s.Errors, e.Error())
+					}
+				})
+			}
+		}
+	}
+
+	err := <-runErr
+	m.update(j, func(s *Status) {
+		finished := time.Now()
+		s.FinishedAt = &finished
+
+		switch {
+		case errors.Is(err, context.Canceled):
+			s.State = StateCancelled
+		case err != nil:
+			s.State = StateFailed
+			s.Error = err.Error()
+		default:
+			s.State = StateFinished
+		}
+	})
+
+	m.prune()
+}
+
+// prune forgets the oldest finished jobs beyond Manager.KeepJobs.
+func (m *Manager) prune() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.KeepJobs <= 0 {
+		return
+	}
+
+	var finished []int
+	for id, j := range m.jobs {
+		if j.status.State != StateRunning {
+			n, _ := strconv.Atoi(id)
+			finished = append(finished, n)
+		}
+	}
+	if len(finished) <= m.KeepJobs {
+		return
+	}
+
+	sort.Ints(finished)
+	for _, n := range finished[:len(finished)-m.KeepJobs] {
+		delete(m.jobs, strconv.Itoa(n))
+	}
+}
+
+func (m *Manager) update(j *job, f func(s *Status)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f(&j.status)
+}
+
+// copyStatus returns a copy of the job status that is safe to use outside
+// of the manager lock.
+func (j *job) copyStatus() Status {
+	s := j.status
+	s.Errors = append([]string{}, j.status.Errors...)
+	s.Request.Search = append([]string{}, j.status.Request.Search...)
+
+	return s
+}
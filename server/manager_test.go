@@ -0,0 +1,218 @@
+//go:build unit
+// +build unit
+
+package server_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"reactor-crw"
+	"reactor-crw/event"
+	"reactor-crw/handler"
+	"reactor-crw/server"
+)
+
+type crawlerStub struct {
+	sources []handler.Source
+	err     error
+}
+
+func (c *crawlerStub) Fetch(_ string, _ []string) ([]handler.Source, error) {
+	return c.sources, c.err
+}
+
+// handlerStub fails on sources with "fail" URL and blocks on each source
+// until release is closed if it's set.
+type handlerStub struct {
+	release chan struct{}
+}
+
+func (h *handlerStub) Process(s handler.Source, progress chan<- int, errs chan<- error) {
+	if h.release != nil {
+		<-h.release
+	}
+	if s.URL == "fail" {
+		errs <- errors.New("cannot process")
+	}
+	progress <- 1
+}
+
+func factory(crw reactor_crw.Crawler, ch handler.ContentHandler) server.ClientFactory {
+	return func(r server.Request, l event.Listener) (*reactor_crw.Client, error) {
+		c := reactor_crw.NewClient(crw, r.Workers, ch)
+		c.Events = l
+
+		return c, nil
+	}
+}
+
+func waitFinished(t *testing.T, m *server.Manager) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	require.NoError(t, m.Wait(ctx), "Jobs weren't finished in time")
+}
+
+func TestManager_Submit(t *testing.T) {
+	t.Log("Given the need to run jobs in the background.")
+	{
+		t.Log("When the job is finished.")
+		{
+			crw := &crawlerStub{sources: []handler.Source{{URL: "ok"}, {URL: "fail"}}}
+			m := server.NewManager(factory(crw, &handlerStub{}))
+
+			s, err := m.Submit(server.Request{Path: "http://joyreactor.cc/tag/test"})
+			require.NoError(t, err, "Wasn't expected an error on submit")
+			require.Equal(t, "1", s.ID)
+			require.Equal(t, server.StateRunning, s.State)
+			require.Equal(t, []string{"image", "gif"}, s.Request.Search)
+
+			waitFinished(t, m)
+
+			s, err = m.Get(s.ID)
+			require.NoError(t, err, "Wasn't expected an error on get")
+			require.Equal(t, server.StateFinished, s.State)
+			require.Equal(t, 2, s.Total)
+			require.Equal(t, 2, s.Done)
+			require.Equal(t, []string{"cannot process"}, s.Errors)
+			require.Equal(t, 1, s.Failed)
+			require.NotNil(t, s.FinishedAt)
+		}
+
+		t.Log("When the job exceeds the limits.")
+		{
+			crw := &crawlerStub{sources: []handler.Source{{URL: "fail"}, {URL: "fail"}, {URL: "fail"}}}
+			m := server.NewManager(factory(crw, &handlerStub{}))
+			m.MaxWorkers, m.MaxErrors, m.KeepJobs = 2, 1, 2
+
+			for i := 0; i < 3; i++ {
+				s, err := m.Submit(server.Request{Path: "http://joyreactor.cc/tag/test", Workers: 100})
+				require.NoError(t, err, "Wasn't expected an error on submit")
+				require.Equal(t, 2, s.Request.Workers, "Expected workers to be capped")
+
+				waitFinished(t, m)
+			}
+
+			list := m.List()
+			require.Len(t, list, 2, "Expected the oldest finished job to be forgotten")
+			require.Equal(t, "2", list[0].ID)
+			require.Equal(t, 3, list[0].Failed)
+			require.Len(t, list[0].Errors, 1, "Expected errors to be limited")
+		}
+
+		t.Log("When the crawler failed.")
+		{
+			crw := &crawlerStub{err: errors.New("cannot crawl")}
+			m := server.NewManager(factory(crw, &handlerStub{}))
+
+			s, err := m.Submit(server.Request{Path: "http://joyreactor.cc/tag/test"})
+			require.NoError(t, err, "Wasn't expected an error on submit")
+
+			waitFinished(t, m)
+
+			s, _ = m.Get(s.ID)
+			require.Equal(t, server.StateFailed, s.State)
+			require.Equal(t, "cannot crawl", s.Error)
+		}
+
+		t.Log("When the request is invalid.")
+		{
+			m := server.NewManager(factory(&crawlerStub{}, &handlerStub{}))
+
+			_, err := m.Submit(server.Request{Path: "invalid"})
+			require.ErrorIs(t, err, server.ErrInvalidRequest)
+			require.Empty(t, m.List())
+		}
+
+		t.Log("When the path points to another site.")
+		{
+			m := server.NewManager(factory(&crawlerStub{}, &handlerStub{}))
+
+			_, err := m.Submit(server.Request{Path: "http://example.com/tag/test"})
+			require.ErrorIs(t, err, server.ErrInvalidRequest)
+
+			_, err = m.Submit(server.Request{Path: "http://anime.reactor.cc/tag/test"})
+			require.NoError(t, err, "Expected fandom subdomains to be allowed")
+
+			waitFinished(t, m)
+		}
+
+		t.Log("When too many jobs are running.")
+		{
+			release := make(chan struct{})
+			crw := &crawlerStub{sources: []handler.Source{{URL: "1"}}}
+			m := server.NewManager(factory(crw, &handlerStub{release: release}))
+			m.MaxJobs = 1
+
+			_, err := m.Submit(server.Request{Path: "http://joyreactor.cc/tag/test"})
+			require.NoError(t, err, "Wasn't expected an error on submit")
+
+			_, err = m.Submit(server.Request{Path: "http://joyreactor.cc/tag/test"})
+			require.ErrorIs(t, err, server.ErrTooManyJobs)
+
+			close(release)
+			waitFinished(t, m)
+
+			_, err = m.Submit(server.Request{Path: "http://joyreactor.cc/tag/test"})
+			require.NoError(t, err, "Expected the job to be accepted once others are finished")
+
+			waitFinished(t, m)
+		}
+	}
+}
+
+func TestManager_Cancel(t *testing.T) {
+	t.Log("Given the need to cancel a running job.")
+	{
+		release := make(chan struct{})
+		crw := &crawlerStub{sources: []handler.Source{{URL: "1"}, {URL: "2"}, {URL: "3"}}}
+		m := server.NewManager(factory(crw, &handlerStub{release: release}))
+
+		t.Log("When the job is running.")
+		{
+			s, err := m.Submit(server.Request{Path: "http://joyreactor.cc/tag/test"})
+			require.NoError(t, err, "Wasn't expected an error on submit")
+
+			require.NoError(t, m.Cancel(s.ID), "Wasn't expected an error on cancel")
+			close(release)
+
+			waitFinished(t, m)
+
+			s, _ = m.Get(s.ID)
+			require.Equal(t, server.StateCancelled, s.State)
+			require.Less(t, s.Done, 3)
+		}
+
+		t.Log("When the job is already finished.")
+		{
+			require.ErrorIs(t, m.Cancel("1"), server.ErrJobFinished)
+		}
+
+		t.Log("When all jobs are cancelled.")
+		{
+			release = make(chan struct{})
+			m := server.NewManager(factory(crw, &handlerStub{release: release}))
+
+			s, err := m.Submit(server.Request{Path: "http://joyreactor.cc/tag/test"})
+			require.NoError(t, err, "Wasn't expected an error on submit")
+
+			m.CancelAll()
+			close(release)
+
+			waitFinished(t, m)
+
+			s, _ = m.Get(s.ID)
+			require.Equal(t, server.StateCancelled, s.State)
+		}
+
+		t.Log("When the job doesn't exist.")
+		{
+			require.ErrorIs(t, m.Cancel("100"), server.ErrJobNotFound)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	jobsPath   = "/jobs"
+	eventsPath = "/events"
+)
+
+// NewHandler creates a REST API handler for the manager. It serves the
+// following routes:
+//
+//	POST   /jobs      submits a new job, the body contains Request
+//	GET    /jobs      lists all jobs
+//	GET    /jobs/{id} returns the job status with its progress and errors
+//	DELETE /jobs/{id} cancels the running job
+//	GET    /jobs/{id}/events streams the job events as Server-Sent Events
+func NewHandler(m *Manager) http.Handler {
+	h := &apiHandler{m: m}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(jobsPath, h.jobs)
+	mux.HandleFunc(jobsPath+"/", h.job)
+
+	return mux
+}
+
+type apiHandler struct {
+	m *Manager
+}
+
+// RequireToken wraps the handler, so it serves only requests with the bearer
+// token in the Authorization header. Other requests get 401 Unauthorized. If
+// the token is empty, the handler is returned as is.
+func RequireToken(h http.Handler, token string) http.Handler {
+	if token == "" {
+		return h
+	}
+
+	expected := []byte("Bearer " + token)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, errors.New("unauthorized"))
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+// errorResponse is returned by all routes in case of an error.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func (h *apiHandler) jobs(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, h.m.List())
+	case http.MethodPost:
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		s, err := h.m.Submit(req)
+		if err != nil {
+			writeError(w, statusCode(err), err)
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, s)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	}
+}
+
+func (h *apiHandler) job(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, jobsPath+"/")
+	if strings.HasSuffix(id, eventsPath) {
+		h.events(w, r, strings.TrimSuffix(id, eventsPath))
+		return
+	}
+
+	if id == "" || strings.Contains(id, "/") {
+		writeError(w, http.StatusNotFound, ErrJobNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s, err := h.m.Get(id)
+		if err != nil {
+			writeError(w, statusCode(err), err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, s)
+	case http.MethodDelete:
+		if err := h.m.Cancel(id); err != nil {
+			writeError(w, statusCode(err), err)
+			return
+		}
+
+		s, _ := h.m.Get(id)
+		writeJSON(w, http.StatusAccepted, s)
+	default:
+		w.Header().Set("Allow", "GET, DELETE")
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	}
+}
+
+// events streams the job events until the job is finished or the client is
+// disconnected. Each event is sent with its type as the event name and its
+// JSON representation as data.
+func (h *apiHandler) events(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+
+	events, cancel, err := h.m.Subscribe(id)
+	if err != nil {
+		writeError(w, statusCode(err), err)
+		return
+	}
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+
+			data, _ := json.Marshal(e)
+			_, _ = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data)
+			flusher.Flush()
+		}
+	}
+}
+
+// statusCode maps manager errors to HTTP status codes.
+func statusCode(err error) int {
+	switch {
+	case errors.Is(err, ErrJobNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrJobFinished):
+		return http.StatusConflict
+	case errors.Is(err, ErrInvalidRequest):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrTooManyJobs):
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, errorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
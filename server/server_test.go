@@ -0,0 +1,189 @@
+//go:build unit
+// +build unit
+
+package server_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"reactor-crw/handler"
+	"reactor-crw/server"
+)
+
+func TestNewHandler(t *testing.T) {
+	crw := &crawlerStub{sources: []handler.Source{{URL: "ok"}}}
+	m := server.NewManager(factory(crw, &handlerStub{}))
+
+	srv := httptest.NewServer(server.NewHandler(m))
+	defer srv.Close()
+
+	t.Log("Given the need to manage jobs over REST API.")
+	{
+		t.Log("When a job is submitted.")
+		{
+			res, err := http.Post(srv.URL+"/jobs", "application/json", strings.NewReader(`{"path": "http://joyreactor.cc/tag/test", "search": ["image"]}`))
+			require.NoError(t, err)
+			defer res.Body.Close()
+
+			require.Equal(t, http.StatusCreated, res.StatusCode)
+
+			var s server.Status
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&s))
+			require.Equal(t, "1", s.ID)
+			require.Equal(t, []string{"image"}, s.Request.Search)
+
+			waitFinished(t, m)
+		}
+
+		t.Log("When the job status is requested.")
+		{
+			res, err := http.Get(srv.URL + "/jobs/1")
+			require.NoError(t, err)
+			defer res.Body.Close()
+
+			require.Equal(t, http.StatusOK, res.StatusCode)
+
+			var s server.Status
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&s))
+			require.Equal(t, server.StateFinished, s.State)
+			require.Equal(t, 1, s.Done)
+		}
+
+		t.Log("When all jobs are requested.")
+		{
+			res, err := http.Get(srv.URL + "/jobs")
+			require.NoError(t, err)
+			defer res.Body.Close()
+
+			var list []server.Status
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&list))
+			require.Len(t, list, 1)
+		}
+
+		t.Log("When a finished job is cancelled.")
+		{
+			req, _ := http.NewRequest(http.MethodDelete, srv.URL+"/jobs/1", nil)
+			res, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer res.Body.Close()
+
+			require.Equal(t, http.StatusConflict, res.StatusCode)
+		}
+
+		t.Log("When requests are invalid.")
+		{
+			requests := []struct {
+				method string
+				path   string
+				body   string
+				code   int
+			}{
+				{http.MethodPost, "/jobs", "{", http.StatusBadRequest},
+				{http.MethodPost, "/jobs", `{"path": "invalid"}`, http.StatusBadRequest},
+				{http.MethodPost, "/jobs", `{"path": "http://example.com/tag/test"}`, http.StatusBadRequest},
+				{http.MethodPut, "/jobs", "", http.StatusMethodNotAllowed},
+				{http.MethodGet, "/jobs/100", "", http.StatusNotFound},
+				{http.MethodPost, "/jobs/1", "", http.StatusMethodNotAllowed},
+			}
+
+			for _, r := range requests {
+				req, _ := http.NewRequest(r.method, srv.URL+r.path, strings.NewReader(r.body))
+				res, err := http.DefaultClient.Do(req)
+				require.NoError(t, err)
+				_ = res.Body.Close()
+
+				require.Equal(t, r.code, res.StatusCode, "%s %s", r.method, r.path)
+			}
+		}
+	}
+}
+
+func TestNewHandler_Events(t *testing.T) {
+	release := make(chan struct{})
+	crw := &crawlerStub{sources: []handler.Source{{URL: "ok"}}}
+	m := server.NewManager(factory(crw, &handlerStub{release: release}))
+
+	srv := httptest.NewServer(server.NewHandler(m))
+	defer srv.Close()
+
+	t.Log("Given the need to stream job events.")
+	{
+		t.Log("When the job is running.")
+		{
+			s, err := m.Submit(server.Request{Path: "http://joyreactor.cc/tag/test"})
+			require.NoError(t, err, "Wasn't expected an error on submit")
+
+			res, err := http.Get(srv.URL + "/jobs/" + s.ID + "/events")
+			require.NoError(t, err)
+			defer res.Body.Close()
+
+			require.Equal(t, http.StatusOK, res.StatusCode)
+			require.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+			m.MaxJobs = 1
+			busy, err := http.Post(srv.URL+"/jobs", "application/json", strings.NewReader(`{"path": "http://joyreactor.cc/tag/test"}`))
+			require.NoError(t, err)
+			_ = busy.Body.Close()
+			require.Equal(t, http.StatusTooManyRequests, busy.StatusCode, "Expected jobs beyond the limit to be rejected")
+
+			close(release)
+
+			body, err := io.ReadAll(res.Body)
+			require.NoError(t, err, "Stream should be closed when the job is finished")
+			require.Contains(t, string(body), "event: item_done\ndata: {")
+			require.Contains(t, string(body), "event: run_finished\ndata: {")
+		}
+
+		t.Log("When the job doesn't exist.")
+		{
+			res, err := http.Get(srv.URL + "/jobs/100/events")
+			require.NoError(t, err)
+			_ = res.Body.Close()
+
+			require.Equal(t, http.StatusNotFound, res.StatusCode)
+		}
+	}
+}
+
+func TestRequireToken(t *testing.T) {
+	m := server.NewManager(factory(&crawlerStub{}, &handlerStub{}))
+
+	srv := httptest.NewServer(server.RequireToken(server.NewHandler(m), "secret"))
+	defer srv.Close()
+
+	t.Log("Given the need to protect the API with a token.")
+	{
+		t.Log("When the token is missing or wrong.")
+		{
+			for _, auth := range []string{"", "Bearer wrong", "secret"} {
+				req, _ := http.NewRequest(http.MethodGet, srv.URL+"/jobs", nil)
+				if auth != "" {
+					req.Header.Set("Authorization", auth)
+				}
+				res, err := http.DefaultClient.Do(req)
+				require.NoError(t, err)
+				_ = res.Body.Close()
+
+				require.Equal(t, http.StatusUnauthorized, res.StatusCode, "Authorization: %s", auth)
+			}
+		}
+
+		t.Log("When the token is valid.")
+		{
+			req, _ := http.NewRequest(http.MethodGet, srv.URL+"/jobs", nil)
+			req.Header.Set("Authorization", "Bearer secret")
+			res, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			_ = res.Body.Close()
+
+			require.Equal(t, http.StatusOK, res.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package reactor_crw
+
+import (
+	"io"
+	"time"
+
+	"reactor-crw/pace"
+)
+
+// ThrottledTransport wraps a Transport and limits the rate of requests made
+// through it. It's safe for concurrent use, so the same instance can be shared
+// between the crawler and content handlers of multiple crawls.
+type ThrottledTransport struct {
+	t        Transport
+	interval time.Duration
+	pacer    pace.Pacer
+}
+
+// NewThrottledTransport creates a new *ThrottledTransport that makes at most
+// rps requests per second using the provided transport.
+func NewThrottledTransport(t Transport, rps float64) *ThrottledTransport {
+	return &ThrottledTransport{
+		t:        t,
+		interval: time.Duration(float64(time.Second) / rps),
+	}
+}
+
+// FetchData waits for the next available request slot and fetches the data
+// using the wrapped transport.
+func (t *ThrottledTransport) FetchData(url string) (io.ReadCloser, error) {
+	t.wait()
+
+	return t.t.FetchData(url)
+}
+
+// Probe waits for the next available request slot and probes the content
+// using the wrapped transport. ErrProbeUnsupported is returned if the wrapped
+// transport isn't a Prober.
+func (t *ThrottledTransport) Probe(url string) (ContentInfo, error) {
+	p, ok := t.t.(Prober)
+	if !ok {
+		return ContentInfo{}, ErrProbeUnsupported
+	}
+
+	t.wait()
+
+	return p.Probe(url)
+}
+
+// wait reserves the next request slot and blocks until it comes.
+func (t *ThrottledTransport) wait() {
+	t.pacer.Wait(t.interval)
+}
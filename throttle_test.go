@@ -0,0 +1,50 @@
+//go:build unit
+// +build unit
+
+package reactor_crw
+
+import (
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestThrottledTransport_FetchData(t *testing.T) {
+	t.Log("Given the need to limit the rate of requests.")
+	{
+		t.Log("When multiple requests are made concurrently.")
+		{
+			trp := &transportMock{}
+			rc := ioutil.NopCloser(strings.NewReader(""))
+			trp.On("FetchData", "url").Return(rc, nil).Times(3)
+
+			tt := NewThrottledTransport(trp, 20)
+
+			start := time.Now()
+
+			var wg sync.WaitGroup
+			errs := make(chan error, 3)
+			for i := 0; i < cap(errs); i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, err := tt.FetchData("url")
+					errs <- err
+				}()
+			}
+			wg.Wait()
+			close(errs)
+
+			for err := range errs {
+				require.NoError(t, err, "Wasn't expected an error during fetch")
+			}
+
+			require.GreaterOrEqual(t, int64(time.Since(start)), int64(100*time.Millisecond))
+			trp.AssertExpectations(t)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package reactor_crw
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Timeouts limits stages of requests and connections to a host. Zero values
+// mean no limit.
+type Timeouts struct {
+	// Connect limits establishing a TCP connection.
+	Connect time.Duration
+
+	// TLSHandshake limits the TLS handshake.
+	TLSHandshake time.Duration
+
+	// ResponseHeader limits waiting for response headers after the request
+	// is sent.
+	ResponseHeader time.Duration
+
+	// MaxConnsPerHost limits the amount of connections to a host including
+	// connections in use.
+	MaxConnsPerHost int
+}
+
+// keepAlive is the keep-alive period of connections, the same as the one of
+// http.DefaultTransport.
+const keepAlive = 30 * time.Second
+
+// Apply sets the timeouts to the transport. Non-zero values override the
+// values of the transport.
+func (t Timeouts) Apply(tr *http.Transport) {
+	if t.Connect > 0 {
+		tr.DialContext = (&net.Dialer{Timeout: t.Connect, KeepAlive: keepAlive}).DialContext
+	}
+	if t.TLSHandshake > 0 {
+		tr.TLSHandshakeTimeout = t.TLSHandshake
+	}
+	if t.ResponseHeader > 0 {
+		tr.ResponseHeaderTimeout = t.ResponseHeader
+	}
+	if t.MaxConnsPerHost > 0 {
+		tr.MaxConnsPerHost = t.MaxConnsPerHost
+		tr.MaxIdleConnsPerHost = t.MaxConnsPerHost
+	}
+}
+
+// StallError returned by a response body when no data arrives within the
+// stall timeout.
+type StallError struct {
+	URL     string
+	Timeout time.Duration
+}
+
+func (e *StallError) Error() string {
+	return fmt.Sprintf("cannot read %s: no data received for %s", e.URL, e.Timeout)
+}
+
+// stallBody aborts the request if a read of the body gets no data within the
+// timeout. The timer runs only while a read is blocked, so the time the reader
+// spends between reads, e.g. waiting for a bandwidth limiter, isn't counted.
+type stallBody struct {
+	io.ReadCloser
+	url     string
+	timeout time.Duration
+	cancel  context.CancelFunc
+	timer   *time.Timer
+
+	mu      sync.Mutex
+	stalled bool
+}
+
+func newStallBody(rc io.ReadCloser, url string, timeout time.Duration, cancel context.CancelFunc) *stallBody {
+	b := &stallBody{ReadCloser: rc, url: url, timeout: timeout, cancel: cancel}
+	b.timer = time.AfterFunc(timeout, func() {
+		b.mu.Lock()
+		b.stalled = true
+		b.mu.Unlock()
+		cancel()
+	})
+	b.timer.Stop()
+
+	return b
+}
+
+func (b *stallBody) Read(p []byte) (int, error) {
+	b.timer.Reset(b.timeout)
+	n, err := b.ReadCloser.Read(p)
+	b.timer.Stop()
+
+	b.mu.Lock()
+	stalled := b.stalled
+	b.mu.Unlock()
+	if err != nil && err != io.EOF && stalled {
+		return n, &StallError{URL: b.url, Timeout: b.timeout}
+	}
+
+	return n, err
+}
+
+func (b *stallBody) Close() error {
+	b.timer.Stop()
+	err := b.ReadCloser.Close()
+	b.cancel()
+
+	return err
+}
@@ -0,0 +1,118 @@
+//go:build unit
+// +build unit
+
+package reactor_crw_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"reactor-crw"
+	"reactor-crw/handler/fs"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeouts_Apply(t *testing.T) {
+	t.Log("Given the need to limit stages of requests.")
+	{
+		t.Log("When timeouts are set.")
+		{
+			tr := http.DefaultTransport.(*http.Transport).Clone()
+			reactor_crw.Timeouts{
+				Connect:         time.Second,
+				TLSHandshake:    2 * time.Second,
+				ResponseHeader:  3 * time.Second,
+				MaxConnsPerHost: 4,
+			}.Apply(tr)
+
+			require.NotNil(t, tr.DialContext)
+			require.Equal(t, 2*time.Second, tr.TLSHandshakeTimeout)
+			require.Equal(t, 3*time.Second, tr.ResponseHeaderTimeout)
+			require.Equal(t, 4, tr.MaxConnsPerHost)
+			require.Equal(t, 90*time.Second, tr.IdleConnTimeout, "Values which aren't set shouldn't be changed")
+		}
+	}
+}
+
+func TestHttpTransport_StallTimeout(t *testing.T) {
+	t.Log("Given the need to abort stalled downloads.")
+	{
+		t.Log("When no data arrives within the timeout.")
+		{
+			done := make(chan struct{})
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = fmt.Fprint(w, "partial")
+				w.(http.Flusher).Flush()
+				select {
+				case <-r.Context().Done():
+				case <-done:
+				}
+			}))
+			defer srv.Close()
+			defer close(done)
+
+			httpTransport := reactor_crw.NewHttpTransport(http.DefaultClient, nil)
+			httpTransport.StallTimeout = 50 * time.Millisecond
+
+			data, err := httpTransport.FetchData(srv.URL)
+			require.NoErrorf(t, err, "Wasn't expected an error during http call")
+			defer data.Close()
+
+			_, err = ioutil.ReadAll(data)
+
+			var se *reactor_crw.StallError
+			require.True(t, errors.As(err, &se), "Expected a stall error, got %v", err)
+		}
+
+		t.Log("When data keeps arriving.")
+		{
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for i := 0; i < 4; i++ {
+					_, _ = fmt.Fprint(w, "chunk")
+					w.(http.Flusher).Flush()
+					time.Sleep(20 * time.Millisecond)
+				}
+			}))
+			defer srv.Close()
+
+			httpTransport := reactor_crw.NewHttpTransport(http.DefaultClient, nil)
+			httpTransport.StallTimeout = 50 * time.Millisecond
+
+			data, err := httpTransport.FetchData(srv.URL)
+			require.NoErrorf(t, err, "Wasn't expected an error during http call")
+			defer data.Close()
+
+			res, err := ioutil.ReadAll(data)
+			require.NoError(t, err)
+			require.Equal(t, "chunkchunkchunkchunk", string(res))
+		}
+
+		t.Log("When the reader is slower than the server.")
+		{
+			content := bytes.Repeat([]byte("a"), 4<<10)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write(content)
+			}))
+			defer srv.Close()
+
+			httpTransport := reactor_crw.NewHttpTransport(http.DefaultClient, nil)
+			httpTransport.StallTimeout = 50 * time.Millisecond
+
+			data, err := httpTransport.FetchData(srv.URL)
+			require.NoErrorf(t, err, "Wasn't expected an error during http call")
+			defer data.Close()
+
+			// Reading takes about 0.4s, far longer than the timeout.
+			res, err := ioutil.ReadAll(fs.NewLimiter(10 << 10).Reader(data))
+			require.NoError(t, err, "Time between reads shouldn't be counted as a stall")
+			require.Equal(t, content, res)
+		}
+	}
+}
@@ -1,57 +1,208 @@
 package reactor_crw
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"math/rand"
 	"net/http"
+	"sync"
+	"time"
+
+	"reactor-crw/logging"
+	"reactor-crw/metrics"
 )
 
+// ContentInfo describes content by the headers of its response.
+type ContentInfo struct {
+	// Type is the MIME type of the content. It's empty if unknown.
+	Type string
+
+	// Length is the size of the content in bytes. It's -1 if unknown.
+	Length int64
+}
+
 // Headers defines a simple wrapper for client headers.
 type Headers map[string]string
 
+// DefaultHeaders returns headers added to each request by HttpTransport unless
+// they are overridden. They're headers of DefaultProfile.
+func DefaultHeaders() Headers {
+	return profiles[DefaultProfile].Values()
+}
+
+// With returns a copy of headers overridden by other headers.
+func (h Headers) With(other Headers) Headers {
+	res := make(Headers, len(h)+len(other))
+	for k, v := range h {
+		res[k] = v
+	}
+	for k, v := range other {
+		res[k] = v
+	}
+
+	return res
+}
+
+// StatusError returned by HttpTransport when the server responds with an
+// error status.
+type StatusError struct {
+	URL  string
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("cannot make request to %s: unexpected status %d", e.URL, e.Code)
+}
+
 // HttpTransport allows making a network request over HTTP protocol. It is a
 // wrapper over HTTP.Client with a set of default params required by the crawler.
 type HttpTransport struct {
+	// Metrics records status codes and latency of requests and the amount of
+	// downloaded bytes. It's optional.
+	Metrics *metrics.Metrics
+
+	// Logger receives a record for each request. It's optional.
+	Logger logging.Logger
+
+	// UserAgents lists User-Agent values rotated per request. A random one
+	// is sent with each request. If it's empty, the User-Agent header is
+	// used.
+	UserAgents []string
+
+	// HeaderRules override headers of requests to matching hosts. All
+	// matching rules are applied in order. Example: a Referer for the media
+	// CDN.
+	HeaderRules []HeaderRule
+
+	// StallTimeout aborts a download if no data arrives within it. Reading
+	// the body returns *StallError then. Zero means no limit.
+	StallTimeout time.Duration
+
 	headers Headers
 	client  *http.Client
+
+	// mu guards rnd, since requests are prepared by concurrent workers.
+	mu  sync.Mutex
+	rnd *rand.Rand
 }
 
 // NewHttpTransport creates a new *HttpTransport with provided client and custom
 // headers. Custom headers can override the default ones.
 func NewHttpTransport(c *http.Client, h Headers) *HttpTransport {
-	var defaultHeaders = Headers{
-		"User-Agent": "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:92.0) Gecko/20100101 Firefox/92.0",
-		"Referer":    "http://joyreactor.cc/",
-		"DNT":        "1",
-	}
-
-	t := &HttpTransport{
-		headers: defaultHeaders,
+	return &HttpTransport{
+		headers: DefaultHeaders().With(h),
 		client:  c,
+		rnd:     rand.New(rand.NewSource(time.Now().UnixNano())),
 	}
-
-	for k, v := range h {
-		t.headers[k] = v
-	}
-
-	return t
 }
 
 // FetchData makes an HTTP request using provided URL and returns the response
 // body as io.ReadCloser interface. Each request will be prepared with provided
 // headers list. The end client is responsible for closing the response body.
+// *StatusError is returned if the response status is 400 or above.
 func (t *HttpTransport) FetchData(url string) (io.ReadCloser, error) {
 	req, err := t.prepareRequest(http.MethodGet, url)
 	if err != nil {
 		return nil, err
 	}
 
+	// The request is aborted when the body stalls.
+	cancel := context.CancelFunc(func() {})
+	if t.StallTimeout > 0 {
+		req, cancel = withCancel(req)
+	}
+
+	start := time.Now()
 	res, err := t.client.Do(req)
 	if err != nil {
+		cancel()
+		t.Metrics.ObserveRequest(req.URL.Host, 0, time.Since(start))
+		t.log().Debug("request failed", "url", url, "error", err)
 		return nil, fmt.Errorf("cannot make request to %s: %w", url, err)
 	}
+	t.Metrics.ObserveRequest(req.URL.Host, res.StatusCode, time.Since(start))
+
+	if res.StatusCode >= http.StatusBadRequest {
+		_ = res.Body.Close()
+		cancel()
+		t.log().Warn("unexpected response status", "url", url, "status", res.StatusCode)
+		return nil, &StatusError{URL: url, Code: res.StatusCode}
+	}
+	t.log().Debug("request finished", "url", url, "status", res.StatusCode, "duration", time.Since(start))
+
+	body := res.Body
+	if t.StallTimeout > 0 {
+		body = newStallBody(res.Body, url, t.StallTimeout, cancel)
+	}
+
+	if t.Metrics == nil {
+		return body, nil
+	}
+
+	return &countingBody{ReadCloser: body, m: t.Metrics}, nil
+}
+
+// countingBody records the amount of bytes read from the response body.
+type countingBody struct {
+	io.ReadCloser
+	m *metrics.Metrics
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.m.AddBytes(n)
 
-	return res.Body, nil
+	return n, err
+}
+
+// Probe makes an HTTP HEAD request using provided URL and returns the content
+// type and length from the response headers.
+func (t *HttpTransport) Probe(url string) (ContentInfo, error) {
+	req, err := t.prepareRequest(http.MethodHead, url)
+	if err != nil {
+		return ContentInfo{}, err
+	}
+
+	start := time.Now()
+	res, err := t.client.Do(req)
+	if err != nil {
+		t.Metrics.ObserveRequest(req.URL.Host, 0, time.Since(start))
+		return ContentInfo{}, fmt.Errorf("cannot make request to %s: %w", url, err)
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+	t.Metrics.ObserveRequest(req.URL.Host, res.StatusCode, time.Since(start))
+
+	if res.StatusCode >= http.StatusBadRequest {
+		return ContentInfo{}, &StatusError{URL: url, Code: res.StatusCode}
+	}
+
+	return ContentInfo{
+		Type:   res.Header.Get("Content-Type"),
+		Length: res.ContentLength,
+	}, nil
+}
+
+// withCancel returns a copy of the request which can be aborted with the
+// returned function.
+func withCancel(req *http.Request) (*http.Request, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(req.Context())
+
+	return req.WithContext(ctx), cancel
+}
+
+func (t *HttpTransport) log() logging.Logger {
+	return logging.OrDiscard(t.Logger)
+}
+
+// userAgent returns a random User-Agent of UserAgents.
+func (t *HttpTransport) userAgent() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.UserAgents[t.rnd.Intn(len(t.UserAgents))]
 }
 
 func (t *HttpTransport) prepareRequest(method, url string) (*http.Request, error) {
@@ -61,7 +212,18 @@ func (t *HttpTransport) prepareRequest(method, url string) (*http.Request, error
 	}
 
 	for k, v := range t.headers {
-		req.Header.Add(k, v)
+		req.Header.Set(k, v)
+	}
+	if len(t.UserAgents) > 0 {
+		req.Header.Set("User-Agent", t.userAgent())
+	}
+	for _, r := range t.HeaderRules {
+		if !r.matches(req.URL.Hostname()) {
+			continue
+		}
+		for k, v := range r.Headers {
+			req.Header.Set(k, v)
+		}
 	}
 
 	return req, nil
@@ -4,13 +4,19 @@
 package reactor_crw_test
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"reactor-crw"
+	"reactor-crw/metrics"
+	"strings"
+	"sync"
 	"testing"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
 )
 
@@ -39,6 +45,50 @@ func TestHttpTransport_FetchData(t *testing.T) {
 			require.Equal(t, expected, string(response))
 		}
 
+		t.Log("When metrics are enabled.")
+		{
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = fmt.Fprintf(w, "response")
+			}))
+			defer srv.Close()
+
+			reg := prometheus.NewRegistry()
+			httpTransport := reactor_crw.NewHttpTransport(http.DefaultClient, nil)
+			httpTransport.Metrics = metrics.New(reg)
+
+			data, err := httpTransport.FetchData(srv.URL)
+			require.NoErrorf(t, err, "Wasn't expected an error during http call")
+
+			_, _ = ioutil.ReadAll(data)
+
+			expected := `
+				# HELP reactor_crw_downloaded_bytes_total Amount of bytes read from HTTP response bodies.
+				# TYPE reactor_crw_downloaded_bytes_total counter
+				reactor_crw_downloaded_bytes_total 8
+			`
+			require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected), "reactor_crw_downloaded_bytes_total"))
+
+			count, err := testutil.GatherAndCount(reg, "reactor_crw_http_requests_total")
+			require.NoError(t, err)
+			require.Equal(t, 1, count, "Expected the request to be recorded")
+		}
+
+		t.Log("When the server responds with an error status.")
+		{
+			srv := httptest.NewServer(http.NotFoundHandler())
+			defer srv.Close()
+
+			httpTransport := reactor_crw.NewHttpTransport(http.DefaultClient, nil)
+
+			data, err := httpTransport.FetchData(srv.URL)
+			require.Nil(t, data, "Wasn't expected a body for an error status")
+
+			var se *reactor_crw.StatusError
+			require.True(t, errors.As(err, &se), "Expected a status error")
+			require.Equal(t, http.StatusNotFound, se.Code)
+			require.Equal(t, srv.URL, se.URL)
+		}
+
 		t.Log("When request cannot be prepared.")
 		{
 			httpTransport := reactor_crw.NewHttpTransport(http.DefaultClient, nil)
@@ -54,5 +104,110 @@ func TestHttpTransport_FetchData(t *testing.T) {
 			_, err := httpTransport.FetchData(" ")
 			require.Error(t, err, "Expected an error during request")
 		}
+
+		t.Log("When headers are overridden for the host.")
+		{
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, "http://img.test/", r.Header.Get("Referer"))
+				require.Equal(t, "agent", r.Header.Get("User-Agent"))
+			}))
+			defer srv.Close()
+
+			httpTransport := reactor_crw.NewHttpTransport(http.DefaultClient, nil)
+			httpTransport.UserAgents = []string{"agent"}
+			httpTransport.HeaderRules = []reactor_crw.HeaderRule{
+				{Hosts: []string{"example.com"}, Headers: reactor_crw.Headers{"Referer": "http://example.com/"}},
+				{Hosts: []string{"127.0.0.1"}, Headers: reactor_crw.Headers{"referer": "http://img.test/"}},
+			}
+
+			data, err := httpTransport.FetchData(srv.URL)
+			require.NoErrorf(t, err, "Wasn't expected an error during http call")
+			_ = data.Close()
+		}
+
+		t.Log("When User-Agents are rotated by concurrent workers.")
+		{
+			agents := []string{"first", "second", "third"}
+
+			var mu sync.Mutex
+			seen := make(map[string]bool)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				seen[r.Header.Get("User-Agent")] = true
+				mu.Unlock()
+			}))
+			defer srv.Close()
+
+			httpTransport := reactor_crw.NewHttpTransport(http.DefaultClient, nil)
+			httpTransport.UserAgents = agents
+
+			var wg sync.WaitGroup
+			errs := make(chan error, 30)
+			for i := 0; i < cap(errs); i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					data, err := httpTransport.FetchData(srv.URL)
+					if err == nil {
+						_ = data.Close()
+					}
+					errs <- err
+				}()
+			}
+			wg.Wait()
+			close(errs)
+
+			for err := range errs {
+				require.NoError(t, err, "Wasn't expected an error during http call")
+			}
+			for ua := range seen {
+				require.Contains(t, agents, ua)
+			}
+		}
+	}
+}
+
+func TestHttpTransport_Probe(t *testing.T) {
+	t.Log("Given the need to probe content before downloading it.")
+	{
+		t.Log("When the content exists.")
+		{
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, http.MethodHead, r.Method)
+				w.Header().Set("Content-Type", "image/png")
+				w.Header().Set("Content-Length", "1024")
+			}))
+			defer srv.Close()
+
+			httpTransport := reactor_crw.NewHttpTransport(http.DefaultClient, nil)
+
+			info, err := httpTransport.Probe(srv.URL)
+			require.NoErrorf(t, err, "Wasn't expected an error during http call")
+			require.Equal(t, reactor_crw.ContentInfo{Type: "image/png", Length: 1024}, info)
+		}
+
+		t.Log("When the content doesn't exist.")
+		{
+			srv := httptest.NewServer(http.NotFoundHandler())
+			defer srv.Close()
+
+			httpTransport := reactor_crw.NewHttpTransport(http.DefaultClient, nil)
+
+			_, err := httpTransport.Probe(srv.URL)
+			require.Error(t, err, "Expected an error for a missing content")
+		}
+	}
+}
+
+func TestHeaders_With(t *testing.T) {
+	t.Log("Given the need to override default headers.")
+	{
+		defaults := reactor_crw.DefaultHeaders()
+		res := defaults.With(reactor_crw.Headers{"Referer": "http://test.com/", "Cookie": "cookie"})
+
+		require.Equal(t, "http://test.com/", res["Referer"])
+		require.Equal(t, "cookie", res["Cookie"])
+		require.Equal(t, defaults["User-Agent"], res["User-Agent"])
+		require.Equal(t, "http://joyreactor.cc/", defaults["Referer"], "Original headers shouldn't be changed")
 	}
 }